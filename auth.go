@@ -0,0 +1,453 @@
+package urlx
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderAuthorization   = "Authorization"
+	HeaderWWWAuthenticate = "WWW-Authenticate"
+)
+
+// BasicAuth HTTP Basic 认证
+func BasicAuth(username, password string) HeaderOption {
+	token := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return HeaderSet(HeaderAuthorization, "Basic "+token)
+}
+
+// BearerAuth Bearer 令牌认证
+func BearerAuth(token string) HeaderOption {
+	return HeaderSet(HeaderAuthorization, "Bearer "+token)
+}
+
+/* Digest 认证 */
+
+// DigestAuth HTTP Digest 认证(RFC 7616), 收到 401 质询后计算摘要并重新发送, 之后的请求复用质询, 跨主机跳转时不认证.
+// 支持 MD5, SHA-256, SHA-512-256 及其 -sess 算法, qop 支持 auth 和 auth-int
+func DigestAuth(username, password string) Option {
+	d := &digestAuth{username: username, password: password}
+	return Transport(d.mw)
+}
+
+// digestAuth Digest 认证的状态, 在使用同一个选项的请求之间共享
+type digestAuth struct {
+	username, password string
+
+	mu         sync.Mutex
+	challenges map[string]*digestNonce // 按 scheme://host 保存的质询
+}
+
+// digestNonce 一个源的质询和已使用的次数
+type digestNonce struct {
+	challenge *digestChallenge
+	nc        int
+}
+
+// digestChallenge 服务器的质询
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm, qop string
+	userhash                             bool
+}
+
+// mw 请求中间件, 有质询则直接认证, 收到新的质询后重新发送
+func (d *digestAuth) mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !sameOrigin(req) {
+			return next.RoundTrip(req)
+		}
+		var used *digestChallenge
+		sent := req
+		if req.Header.Get(HeaderAuthorization) == "" {
+			if auth, c, err := d.authorize(req); err == nil && auth != "" {
+				sent, used = req.Clone(req.Context()), c
+				sent.Header.Set(HeaderAuthorization, auth)
+			}
+		}
+
+		resp, err := next.RoundTrip(sent)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+
+		// 使用相同的 nonce 仍然被拒绝, 说明用户名或密码错误
+		challenge := parseDigestChallenge(resp.Header.Values(HeaderWWWAuthenticate))
+		if challenge == nil || used != nil && used.nonce == challenge.nonce {
+			return resp, nil
+		}
+		retry, err := cloneForResend(req)
+		if err != nil {
+			return resp, nil
+		}
+		d.mu.Lock()
+		if d.challenges == nil {
+			d.challenges = map[string]*digestNonce{}
+		}
+		d.challenges[originOf(req.URL)] = &digestNonce{challenge: challenge}
+		d.mu.Unlock()
+
+		auth, _, err := d.authorize(retry)
+		if err != nil {
+			return resp, nil
+		}
+		drain(resp.Body)
+		retry.Header.Set(HeaderAuthorization, auth)
+		return next.RoundTrip(retry)
+	})
+}
+
+// authorize 使用请求的源保存的质询计算 Authorization, 没有质询返回空
+func (d *digestAuth) authorize(req *http.Request) (auth string, c *digestChallenge, err error) {
+	d.mu.Lock()
+	state := d.challenges[originOf(req.URL)]
+	if state == nil {
+		d.mu.Unlock()
+		return
+	}
+	state.nc++
+	c, nc := state.challenge, fmt.Sprintf("%08x", state.nc)
+	d.mu.Unlock()
+
+	h := digestHash(c.algorithm)
+	if h == nil {
+		return "", c, fmt.Errorf("digest: unsupported algorithm %q", c.algorithm)
+	}
+	sum := func(parts ...string) string {
+		hh := h()
+		hh.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	cnonce := randomHex(16)
+	uri := req.URL.RequestURI()
+	ha1 := sum(d.username, c.realm, d.password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = sum(ha1, c.nonce, cnonce)
+	}
+
+	ha2 := sum(req.Method, uri)
+	if c.qop == "auth-int" {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return "", c, err
+		}
+		hh := h()
+		hh.Write(body)
+		ha2 = sum(req.Method, uri, hex.EncodeToString(hh.Sum(nil)))
+	}
+
+	var response string
+	if c.qop == "" {
+		response = sum(ha1, c.nonce, ha2)
+	} else {
+		response = sum(ha1, c.nonce, nc, cnonce, c.qop, ha2)
+	}
+
+	username := d.username
+	if c.userhash {
+		username = sum(d.username, c.realm)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q, response=%q`, username, c.realm, c.nonce, uri, response)
+	if c.algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", c.algorithm)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, ", opaque=%q", c.opaque)
+	}
+	if c.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce=%q`, c.qop, nc, cnonce)
+	}
+	if c.userhash {
+		b.WriteString(", userhash=true")
+	}
+	return b.String(), c, nil
+}
+
+// parseDigestChallenge 选择第一个支持的 Digest 质询
+func parseDigestChallenge(values []string) *digestChallenge {
+	for _, value := range values {
+		scheme, params := parseAuthParams(value)
+		if !strings.EqualFold(scheme, "Digest") || params["nonce"] == "" || digestHash(params["algorithm"]) == nil {
+			continue
+		}
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		if qop := params["qop"]; qop != "" {
+			for _, q := range strings.Split(qop, ",") {
+				switch q = strings.TrimSpace(q); {
+				case q == "auth":
+					c.qop = q
+				case q == "auth-int" && c.qop == "":
+					c.qop = q
+				}
+			}
+			if c.qop == "" {
+				continue
+			}
+		}
+		return c
+	}
+	return nil
+}
+
+// digestHash 算法对应的摘要, 不支持返回 nil
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	case "SHA-512-256":
+		return sha512.New512_256
+	}
+	return nil
+}
+
+// parseAuthParams 解析 WWW-Authenticate 中的方案和参数
+func parseAuthParams(value string) (scheme string, params map[string]string) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexByte(value, ' '); i > 0 {
+		scheme, value = value[:i], value[i+1:]
+	} else {
+		return value, nil
+	}
+
+	params = map[string]string{}
+	for value != "" {
+		value = strings.TrimLeft(value, " ,")
+		i := strings.IndexByte(value, '=')
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(value[:i]))
+		value = strings.TrimLeft(value[i+1:], " ")
+
+		var v string
+		if strings.HasPrefix(value, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(value) && value[j] != '"'; j++ {
+				if value[j] == '\\' && j+1 < len(value) {
+					j++
+				}
+				b.WriteByte(value[j])
+			}
+			if j < len(value) {
+				j++
+			}
+			v, value = b.String(), value[j:]
+		} else if j := strings.IndexByte(value, ','); j >= 0 {
+			v, value = strings.TrimSpace(value[:j]), value[j:]
+		} else {
+			v, value = strings.TrimSpace(value), ""
+		}
+		params[key] = v
+	}
+	return
+}
+
+/* 令牌刷新 */
+
+// Token 访问令牌
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"` // 有效秒数
+	Expiry       time.Time `json:"-"`                    // 过期时间, 为空则不过期
+}
+
+// Valid 令牌是否有效, 提前10秒视为过期
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > 10*time.Second)
+}
+
+// TokenSource 令牌来源
+type TokenSource interface {
+	// Token 获取有效的令牌
+	Token(ctx context.Context) (*Token, error)
+	// Invalidate 令牌被服务器拒绝, 下次获取时刷新
+	Invalidate(token *Token)
+}
+
+// TokenAuth 使用令牌认证, 收到 401 后刷新令牌并重新发送一次, 跨主机跳转时不发送令牌
+func TokenAuth(source TokenSource) Option {
+	return Transport(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if !sameOrigin(req) {
+				return next.RoundTrip(req)
+			}
+			token, err := source.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			sent := req.Clone(req.Context())
+			sent.Header.Set(HeaderAuthorization, token.authorization())
+			resp, err := next.RoundTrip(sent)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			retry, err := cloneForResend(req)
+			if err != nil {
+				return resp, nil
+			}
+			source.Invalidate(token)
+			if token, err = source.Token(req.Context()); err != nil {
+				drain(resp.Body)
+				return nil, err
+			}
+			drain(resp.Body)
+			retry.Header.Set(HeaderAuthorization, token.authorization())
+			return next.RoundTrip(retry)
+		})
+	})
+}
+
+// authorization Authorization 的值
+func (t *Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// OAuth2 OAuth2 令牌来源, 设置了 RefreshToken 使用 refresh_token 方式, 否则使用 client_credentials 方式.
+// 令牌在过期前缓存, 刷新后服务器返回新的 refresh_token 则替换
+type OAuth2 struct {
+	TokenURL     string       // 令牌地址
+	ClientID     string       // 客户端ID
+	ClientSecret string       // 客户端密钥
+	Scopes       []string     // 权限范围
+	RefreshToken string       // 刷新令牌
+	ParamsAuth   bool         // 客户端ID和密钥放在表单中, 默认使用 Basic 认证
+	Client       *http.Client // 获取令牌使用的客户端
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// Token 获取有效的令牌
+func (o *OAuth2) Token(ctx context.Context) (*Token, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token.Valid() {
+		return o.token, nil
+	}
+
+	form := url.Values{}
+	if o.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", o.RefreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+
+	req := New(ctx).Method(MethodPost).Url(o.TokenURL).HeaderWith(AcceptJSON).ProcessWith(CheckStatus)
+	if o.ParamsAuth {
+		form.Set("client_id", o.ClientID)
+		form.Set("client_secret", o.ClientSecret)
+	} else if o.ClientID != "" {
+		req.HeaderWith(BasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret)))
+	}
+	if o.Client != nil {
+		req.UseClient(o.Client)
+	}
+
+	var token Token
+	err := req.FormValues(form).Process(func(resp *http.Response) error {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &token)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" {
+		o.RefreshToken = token.RefreshToken
+	}
+	o.token = &token
+	return o.token, nil
+}
+
+// Invalidate 令牌被拒绝, 下次获取时刷新
+func (o *OAuth2) Invalidate(token *Token) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token == token {
+		o.token = nil
+	}
+}
+
+// cloneForResend 复制请求以便重新发送, 请求内容无法重新读取则返回错误
+func cloneForResend(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, ErrBodyNotReplayable
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// readRequestBody 通过 GetBody 读取请求内容, 不消耗请求的 Body
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer closes(body)
+	return io.ReadAll(body)
+}
+
+// randomHex 随机的十六进制字符串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
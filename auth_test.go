@@ -0,0 +1,156 @@
+package urlx
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDigestAuth(t *testing.T) {
+	const realm, nonce = "test", "n1"
+	md5hex := func(s string) string { sum := md5.Sum([]byte(s)); return hex.EncodeToString(sum[:]) }
+	var challenges int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, params := parseAuthParams(r.Header.Get(HeaderAuthorization))
+		ha1 := md5hex("user:" + realm + ":pass")
+		ha2 := md5hex(r.Method + ":" + params["uri"])
+		want := md5hex(strings.Join([]string{ha1, nonce, params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if params == nil || params["response"] != want {
+			atomic.AddInt32(&challenges, 1)
+			rw.Header().Set(HeaderWWWAuthenticate, fmt.Sprintf(`Digest realm=%q, qop="auth,auth-int", nonce=%q, opaque="o"`, realm, nonce))
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = rw.Write(body)
+	}))
+	defer closer()
+
+	s := NewSession(DigestAuth("user", "pass")).BaseURL(addr)
+	for i := 0; i < 2; i++ {
+		data, err := s.New(context.TODO()).Method(MethodPost).Url("/a?b=c").FormValues(map[string][]string{"k": {"v"}}).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		eq(t, [][2]any{{string(data), "k=v"}})
+	}
+	eq(t, [][2]any{{atomic.LoadInt32(&challenges), int32(1)}})
+
+	status := 0
+	err := New(context.TODO(), DigestAuth("user", "wrong")).Url(addr).Process(func(resp *http.Response) error {
+		status = resp.StatusCode
+		return nil
+	})
+	eq(t, [][2]any{{err, nil}, {status, http.StatusUnauthorized}})
+}
+
+func TestTokenAuth(t *testing.T) {
+	var issued int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, _, _ := r.BasicAuth()
+			if user != "id" || r.FormValue("grant_type") != "refresh_token" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddInt32(&issued, 1)
+			rw.Header().Set(HeaderContentType, "application/json")
+			_, _ = fmt.Fprintf(rw, `{"access_token":"t%d","refresh_token":"r%d","expires_in":3600}`, n, n)
+			return
+		}
+		// 第一个令牌被服务器提前吊销
+		if auth := r.Header.Get(HeaderAuthorization); auth != "Bearer t2" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = rw.Write([]byte("ok"))
+	}))
+	defer closer()
+
+	source := &OAuth2{TokenURL: addr + "/token", ClientID: "id", ClientSecret: "secret", RefreshToken: "r0"}
+	data, err := New(context.TODO(), TokenAuth(source)).Url(addr + "/api").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "ok"}, {atomic.LoadInt32(&issued), int32(2)}, {source.RefreshToken, "r2"}})
+}
+
+func TestAuthCrossHostRedirect(t *testing.T) {
+	var received []string
+	other, closeOther := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path+"="+r.Header.Get(HeaderAuthorization))
+	}))
+	defer closeOther()
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path+"="+r.Header.Get(HeaderAuthorization))
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(rw, r, "/landing", http.StatusFound)
+		case "/cross":
+			http.Redirect(rw, r, other+"/landing", http.StatusFound)
+		}
+	}))
+	defer closer()
+
+	source := &staticToken{&Token{AccessToken: "SECRET"}}
+	for _, option := range []Option{TokenAuth(source), Transport(HeaderMw(BearerAuth("SECRET")))} {
+		received = nil
+		for _, path := range []string{"/same", "/cross"} {
+			if err := New(context.TODO(), option).Url(addr + path).Process(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		eq(t, [][2]any{{strings.Join(received, ","), "/same=Bearer SECRET,/landing=Bearer SECRET,/cross=Bearer SECRET,/landing="}})
+	}
+}
+
+// staticToken 固定的令牌
+type staticToken struct{ token *Token }
+
+func (s *staticToken) Token(context.Context) (*Token, error) { return s.token, nil }
+func (s *staticToken) Invalidate(*Token)                     {}
+
+func TestDigestAuthPerOrigin(t *testing.T) {
+	var received []string
+	other, closeOther := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(HeaderAuthorization))
+	}))
+	defer closeOther()
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAuthorization) == "" {
+			rw.Header().Set(HeaderWWWAuthenticate, `Digest realm="a", qop="auth", nonce="n1"`)
+			rw.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer closer()
+
+	// 质询按源保存, 另一个主机的第一个请求不会使用之前主机的质询预先认证
+	s := NewSession(DigestAuth("user", "pass"))
+	for _, u := range []string{addr, other} {
+		if err := s.New(context.TODO()).Url(u).Process(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eq(t, [][2]any{{strings.Join(received, ","), ""}, {len(received), 1}})
+}
+
+func TestSameOrigin(t *testing.T) {
+	first, _ := http.NewRequest(MethodGet, "https://example.com/a", nil)
+	hop := func(target string) *http.Request {
+		req, _ := http.NewRequest(MethodGet, target, nil)
+		req.Response = &http.Response{Request: first}
+		return req
+	}
+	eq(t, [][2]any{
+		{sameOrigin(first), true},
+		{sameOrigin(hop("https://EXAMPLE.com/b")), true},
+		{sameOrigin(hop("http://example.com/b")), false},
+		{sameOrigin(hop("https://other.com/b")), false},
+	})
+}
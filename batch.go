@@ -0,0 +1,250 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// BatchItem 批量执行的一项
+type BatchItem struct {
+	Request *Request // 请求, 执行时使用批量的 Context
+	Process Process  // 响应处理
+}
+
+// Batch 批量执行请求, 限制并发数和单个主机的并发数
+type Batch struct {
+	concurrency int  // 最大并发数
+	perHost     int  // 单个主机的最大并发数, 0不限制
+	failFast    bool // 出错后取消其余请求
+}
+
+// NewBatch 创建批量执行器, concurrency 最大并发数(最小为1)
+func NewBatch(concurrency int) *Batch {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Batch{concurrency: concurrency}
+}
+
+// PerHost 单个主机的最大并发数, 0不限制
+func (b *Batch) PerHost(n int) *Batch {
+	b.perHost = n
+	return b
+}
+
+// FailFast 第一个错误出现后取消其余请求, 默认执行全部请求并收集错误
+func (b *Batch) FailFast(failFast bool) *Batch {
+	b.failFast = failFast
+	return b
+}
+
+// Run 执行请求, errs 按顺序与请求对应, 有错误时 err 为 *BatchError
+func (b *Batch) Run(ctx context.Context, items ...BatchItem) (errs []error, err error) {
+	errs, err = b.Generate(ctx, func(i int) (BatchItem, bool) {
+		if i < len(items) {
+			return items[i], true
+		}
+		return BatchItem{}, false
+	})
+	if len(errs) < len(items) {
+		// 快速失败后未执行的请求
+		cause := context.Canceled
+		if ctx != nil && ctx.Err() != nil {
+			cause = ctx.Err()
+		}
+		for len(errs) < len(items) {
+			errs = append(errs, cause)
+		}
+		err = newBatchError(errs)
+	}
+	return
+}
+
+// Generate 从生成器依次获取请求并执行, next 返回 false 表示结束, Context 结束后不再获取.
+// 等待单个主机并发的请求不占用并发数, 其他主机的请求可以先执行, 最多预先获取 concurrency 个请求等待
+func (b *Batch) Generate(ctx context.Context, next func(i int) (BatchItem, bool)) ([]error, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		errs   []error
+		sem    = make(chan struct{}, b.concurrency) // 执行中
+		queued = make(chan struct{}, b.concurrency) // 已获取, 等待执行
+		hosts  = &hostLimiter{limit: b.perHost, sems: map[string]chan struct{}{}}
+	)
+
+	setErr := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if errs[i] = err; err != nil && b.failFast {
+			cancel()
+		}
+	}
+
+	for i := 0; ctx.Err() == nil; i++ {
+		select {
+		case queued <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		item, ok := next(i)
+		if !ok {
+			<-queued
+			break
+		}
+		mu.Lock()
+		errs = append(errs, nil)
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			setErr(i, b.run(ctx, hosts, sem, queued, item))
+		}(i, item)
+	}
+	wg.Wait()
+
+	return errs, newBatchError(errs)
+}
+
+// run 执行一个请求, 先获取主机的并发数再获取总的并发数, 然后离开等待队列
+func (b *Batch) run(ctx context.Context, hosts *hostLimiter, sem, queued chan struct{}, item BatchItem) error {
+	dequeue := func() { <-queued }
+	defer func() {
+		if dequeue != nil {
+			dequeue()
+		}
+	}()
+
+	if item.Request == nil {
+		return nil
+	}
+	r := item.Request.WithContext(ctx)
+	if err := r.prepare(); err != nil {
+		return err
+	}
+
+	if hosts.limit > 0 {
+		rawURL, err := r.buildURL()
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		release, err := hosts.acquire(ctx, u.Host)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	select {
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	dequeue()
+	dequeue = nil
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return r.execute(item.Process)
+}
+
+// hostLimiter 单个主机的并发限制
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	sems  map[string]chan struct{}
+}
+
+func (h *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	h.mu.Lock()
+	sem, ok := h.sems[host]
+	if !ok {
+		sem = make(chan struct{}, h.limit)
+		h.sems[host] = sem
+	}
+	h.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// BatchError 批量执行的错误
+type BatchError struct {
+	Errs   []error // 与请求一一对应, 成功的为空
+	Failed int     // 失败的数量
+}
+
+// newBatchError 没有错误返回 nil
+func newBatchError(errs []error) error {
+	e := &BatchError{Errs: errs}
+	for _, err := range errs {
+		if err != nil {
+			e.Failed++
+		}
+	}
+	if e.Failed == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error 错误信息, 包含第一个错误
+func (e *BatchError) Error() string {
+	for i, err := range e.Errs {
+		if err != nil {
+			return fmt.Sprintf("%d of %d requests failed, #%d: %v", e.Failed, len(e.Errs), i, err)
+		}
+	}
+	return fmt.Sprintf("%d of %d requests failed", e.Failed, len(e.Errs))
+}
+
+// Is 任意一个错误匹配 target, Go 1.20 之前 errors.Is 不支持 Unwrap() []error
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if err != nil && errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 第一个可以转换为 target 的错误, Go 1.20 之前 errors.As 不支持 Unwrap() []error
+func (e *BatchError) As(target any) bool {
+	for _, err := range e.Errs {
+		if err != nil && errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap 所有的错误, Go 1.20 及以上的 errors.Is 和 errors.As 使用
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, e.Failed)
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
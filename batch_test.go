@@ -0,0 +1,85 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	var inflight, peak int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); p = atomic.LoadInt32(&peak) {
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Query().Get("i") == "3" {
+			rw.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = rw.Write([]byte(r.URL.Query().Get("i")))
+	}))
+	defer closer()
+
+	base := New(nil).Url(addr).ProcessWith(CheckStatus)
+	out := make([]string, 8)
+	items := make([]BatchItem, len(out))
+	for i := range items {
+		i := i
+		items[i] = BatchItem{
+			Request: base.Clone().QuerySet("i", strconv.Itoa(i)),
+			Process: func(resp *http.Response) error {
+				data, err := io.ReadAll(resp.Body)
+				out[i] = string(data)
+				return err
+			},
+		}
+	}
+
+	errs, err := NewBatch(4).PerHost(2).Run(context.TODO(), items...)
+	var be *BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("want *BatchError, got %v", err)
+	}
+	eq(t, [][2]any{
+		{be.Failed, 1},
+		{StatusOf(errs[3]), http.StatusNotFound},
+		{errs[7], nil},
+		{out[7], "7"},
+		{atomic.LoadInt32(&peak) <= 2, true},
+	})
+
+	errs, err = NewBatch(1).FailFast(true).Run(context.TODO(), items...)
+	eq(t, [][2]any{{errs[2], nil}, {StatusOf(errs[3]), http.StatusNotFound}, {errs[4], context.Canceled}, {errors.Is(err, context.Canceled), true}})
+}
+
+func TestBatchPerHost(t *testing.T) {
+	slow, closeSlow := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer closeSlow()
+	fast, closeFast := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer closeFast()
+
+	// 慢主机的请求等待主机并发时不占用并发数, 快主机的请求不需要等待
+	var done []string
+	var mu sync.Mutex
+	item := func(name, addr string) BatchItem {
+		return BatchItem{Request: New(nil).Url(addr), Process: func(*http.Response) error {
+			mu.Lock()
+			done = append(done, name)
+			mu.Unlock()
+			return nil
+		}}
+	}
+	if _, err := NewBatch(2).PerHost(1).Run(context.TODO(), item("slow1", slow), item("slow2", slow), item("fast", fast)); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{len(done), 3}, {done[0], "fast"}})
+}
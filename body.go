@@ -0,0 +1,148 @@
+package urlx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultBodyBufferSize 无法回退的请求内容默认最多缓存的字节数
+const DefaultBodyBufferSize = 8 << 20
+
+// ErrBodyNotReplayable 请求内容无法重复读取，不能重试或者跟随 307/308 跳转
+var ErrBodyNotReplayable = errors.New("request body is not replayable")
+
+// BodyBuffer 设置无法回退的请求内容(比如管道)最多缓存的字节数, 0 使用默认值, 小于0不缓存
+func (c *Request) BodyBuffer(limit int64) *Request {
+	c.bodyLimit = limit
+	return c
+}
+
+// replayBody 可以重复读取的请求内容
+type replayBody struct {
+	size   int64                         // 内容长度, -1 表示未知
+	open   func() (io.ReadCloser, error) // 获取一个从头开始的读取器
+	closer io.Closer                     // 原始内容的关闭方法
+}
+
+// newReplayBody 根据原始内容的类型选择回退方式: 字节缓冲直接复用, 可定位的内容(文件等)按位置独立读取, 其他的缓存到内存
+func newReplayBody(r io.Reader, limit int64) (*replayBody, error) {
+	if r == nil || r == http.NoBody {
+		return nil, nil
+	}
+
+	b := &replayBody{size: -1}
+	if closer, ok := r.(io.Closer); ok {
+		b.closer = closer
+	}
+
+	if buf, ok := r.(*bytes.Buffer); ok {
+		b.setBytes(buf.Bytes())
+		return b, nil
+	}
+
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		if pos, end, err := seekRange(seeker); err == nil {
+			b.size = end - pos
+			ra, ok := r.(io.ReaderAt)
+			if !ok {
+				ra = &seekReaderAt{r: seeker}
+			}
+			// 每次返回独立的读取器, GetBody 不会影响正在发送的内容
+			b.open = func() (io.ReadCloser, error) { return io.NopCloser(io.NewSectionReader(ra, pos, end-pos)), nil }
+			return b, nil
+		}
+	}
+
+	if limit == 0 {
+		limit = DefaultBodyBufferSize
+	}
+
+	var head []byte
+	if limit > 0 {
+		data, err := io.ReadAll(io.LimitReader(r, limit+1))
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+		if int64(len(data)) <= limit {
+			b.setBytes(data)
+			return b, nil
+		}
+		head = data
+	}
+
+	// 超出缓存大小, 只能读取一次
+	used := false
+	b.open = func() (io.ReadCloser, error) {
+		if used {
+			return nil, ErrBodyNotReplayable
+		}
+		used = true
+		return io.NopCloser(io.MultiReader(bytes.NewReader(head), r)), nil
+	}
+	return b, nil
+}
+
+// setBytes 使用字节内容
+func (b *replayBody) setBytes(data []byte) {
+	b.size = int64(len(data))
+	b.open = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+}
+
+// apply 将内容设置到请求上，并设置 GetBody 以便跳转时重新发送
+func (b *replayBody) apply(req *http.Request) error {
+	if b == nil {
+		return nil
+	}
+	if b.size == 0 {
+		req.Body, req.GetBody = http.NoBody, func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return nil
+	}
+	body, err := b.open()
+	if err != nil {
+		return err
+	}
+	req.Body, req.GetBody = body, b.open
+	if b.size > 0 {
+		req.ContentLength = b.size
+	}
+	return nil
+}
+
+// Close 关闭原始内容
+func (b *replayBody) Close() error {
+	if b == nil || b.closer == nil {
+		return nil
+	}
+	return b.closer.Close()
+}
+
+// seekReaderAt 通过定位实现 io.ReaderAt, 多个读取器交替读取时互不影响
+type seekReaderAt struct {
+	mu sync.Mutex
+	r  io.ReadSeeker
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(s.r, p)
+}
+
+// seekRange 获取当前位置和结束位置
+func seekRange(seeker io.Seeker) (pos, end int64, err error) {
+	if pos, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+		return
+	}
+	if end, err = seeker.Seek(0, io.SeekEnd); err != nil {
+		return
+	}
+	_, err = seeker.Seek(pos, io.SeekStart)
+	return
+}
@@ -0,0 +1,73 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// onceReader 不可回退的读取器
+type onceReader struct{ io.Reader }
+
+func TestReplayBody(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(rw, r, "/echo", http.StatusTemporaryRedirect)
+			return
+		}
+		if atomic.AddInt32(&hits, 1) == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = io.Copy(rw, r.Body)
+	}))
+	defer closer()
+
+	newBody := func() Body {
+		return func() (string, io.Reader, error) { return "text/plain", onceReader{strings.NewReader("payload")}, nil }
+	}
+	retry := RetryPolicy{MaxAttempts: 2, Backoff: BackoffConstant(time.Millisecond)}
+
+	data, err := New(context.TODO()).Method(MethodPost).Url(addr + "/echo").Body(newBody()).RetryWith(retry).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "payload"}})
+
+	data, err = New(context.TODO()).Method(MethodPost).Url(addr + "/redirect").Body(newBody()).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "payload"}})
+
+	atomic.StoreInt32(&hits, 0)
+	err = New(context.TODO()).Method(MethodPost).Url(addr + "/echo").Body(newBody()).RetryWith(retry).BodyBuffer(-1).Process(nil)
+	if !errors.Is(err, ErrBodyNotReplayable) {
+		t.Fatalf("want ErrBodyNotReplayable, got %v", err)
+	}
+}
+
+// seekOnlyReader 可定位但没有实现 io.ReaderAt 的读取器
+type seekOnlyReader struct{ io.ReadSeeker }
+
+func TestReplayBodySeeker(t *testing.T) {
+	b, err := newReplayBody(seekOnlyReader{strings.NewReader("0123456789")}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := b.open()
+	head := make([]byte, 4)
+	_, _ = io.ReadFull(first, head)
+
+	second, _ := b.open()
+	all, _ := io.ReadAll(second)
+	rest, _ := io.ReadAll(first)
+	eq(t, [][2]any{{b.size, int64(10)}, {string(all), "0123456789"}, {string(head) + string(rest), "0123456789"}})
+}
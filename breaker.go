@@ -0,0 +1,215 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开, 请求未发送直接失败
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // 关闭, 正常请求
+	CircuitOpen                         // 打开, 快速失败
+	CircuitHalfOpen                     // 半开, 放行少量请求试探是否恢复
+)
+
+// String 状态名称
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker 熔断器, 默认按主机分别熔断, 可以在多个请求和会话之间共享.
+// 连续失败达到阈值后打开, 冷却时间过后进入半开状态放行试探请求, 试探成功则关闭, 失败则重新打开
+type CircuitBreaker struct {
+	threshold int                                       // 连续失败多少次后打开
+	coolDown  time.Duration                             // 打开后多久进入半开
+	probes    int                                       // 半开时允许同时进行的试探请求数
+	failure   func(resp *http.Response, err error) bool // 是否失败
+	key       func(req *http.Request) string            // 熔断的键
+	now       func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewCircuitBreaker 创建熔断器, threshold 连续失败次数(最小为1), coolDown 打开后的冷却时间
+func NewCircuitBreaker(threshold int, coolDown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, coolDown: coolDown, probes: 1, now: time.Now, circuits: map[string]*circuit{}}
+}
+
+// KeyBy 自定义熔断的键, 默认为请求的主机(包括端口)
+func (b *CircuitBreaker) KeyBy(key func(req *http.Request) string) *CircuitBreaker {
+	b.key = key
+	return b
+}
+
+// FailureBy 自定义失败的判断, 默认网络错误或者 5xx 响应为失败
+func (b *CircuitBreaker) FailureBy(failure func(resp *http.Response, err error) bool) *CircuitBreaker {
+	b.failure = failure
+	return b
+}
+
+// HalfOpenProbes 半开时允许同时进行的试探请求数, 默认1
+func (b *CircuitBreaker) HalfOpenProbes(n int) *CircuitBreaker {
+	if n < 1 {
+		n = 1
+	}
+	b.probes = n
+	return b
+}
+
+// Breaker 使用熔断器
+func Breaker(b *CircuitBreaker) Option {
+	return Transport(b.Mw)
+}
+
+// Mw 请求中间件, 熔断器打开时返回 ErrCircuitOpen
+func (b *CircuitBreaker) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		key := b.keyOf(req)
+		probe, ok := b.allow(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil && errors.Is(req.Context().Err(), context.Canceled) {
+			b.release(key, probe)
+			return resp, err
+		}
+		b.record(key, probe, !b.isFailure(resp, err))
+		return resp, err
+	})
+}
+
+// State 获取熔断器状态
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= b.coolDown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Reset 重置为关闭状态
+func (b *CircuitBreaker) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, key)
+}
+
+// allow 是否放行请求, 半开时放行的试探请求返回本次半开的序号 probe, 否则 probe 为0
+func (b *CircuitBreaker) allow(key string) (probe uint64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	switch c.state {
+	case CircuitOpen:
+		if b.now().Sub(c.openedAt) < b.coolDown {
+			return 0, false
+		}
+		c.state, c.probing = CircuitHalfOpen, 0
+		c.halfOpens++
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probing >= b.probes {
+			return 0, false
+		}
+		c.probing++
+		return c.halfOpens, true
+	}
+	return 0, true
+}
+
+// record 记录请求结果, 半开时只有本次半开的试探请求改变状态, 之前放行的请求的结果被忽略
+func (b *CircuitBreaker) record(key string, probe uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	switch c.state {
+	case CircuitHalfOpen:
+		if probe == 0 || probe != c.halfOpens {
+			return
+		}
+		c.probing--
+		if success {
+			c.state, c.failures = CircuitClosed, 0
+		} else {
+			c.state, c.openedAt = CircuitOpen, b.now()
+		}
+	case CircuitClosed:
+		if success {
+			c.failures = 0
+		} else if c.failures++; c.failures >= b.threshold {
+			c.state, c.openedAt = CircuitOpen, b.now()
+		}
+	}
+}
+
+// release 请求被取消, 不计入结果
+func (b *CircuitBreaker) release(key string, probe uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuit(key); c.state == CircuitHalfOpen && probe != 0 && probe == c.halfOpens && c.probing > 0 {
+		c.probing--
+	}
+}
+
+func (b *CircuitBreaker) isFailure(resp *http.Response, err error) bool {
+	if b.failure != nil {
+		return b.failure(resp, err)
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500
+}
+
+func (b *CircuitBreaker) keyOf(req *http.Request) string {
+	if b.key != nil {
+		return b.key(req)
+	}
+	return req.URL.Host
+}
+
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// circuit 单个键的熔断状态
+type circuit struct {
+	state    CircuitState
+	failures int       // 连续失败次数
+	openedAt time.Time // 打开的时间
+	probing  int       // 半开时进行中的试探请求数
+
+	halfOpens uint64 // 进入半开的次数, 用于区分试探请求属于哪次半开
+}
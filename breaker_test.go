@@ -0,0 +1,72 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing, hits int32 = 1, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	now := time.Now()
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	req := New(context.TODO(), Breaker(breaker)).Url(srv.URL).ProcessWith(CheckStatus).TryAt(time.Millisecond)
+	if err := req.Process(nil); StatusOf(err) != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %v", err)
+	}
+	eq(t, [][2]any{{breaker.State(u.Host), CircuitOpen}, {atomic.LoadInt32(&hits), int32(2)}})
+
+	if err := req.Process(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want ErrCircuitOpen, got %v", err)
+	}
+	eq(t, [][2]any{{atomic.LoadInt32(&hits), int32(2)}})
+
+	now = now.Add(time.Minute)
+	eq(t, [][2]any{{breaker.State(u.Host), CircuitHalfOpen}})
+	atomic.StoreInt32(&failing, 0)
+	if err := req.Process(nil); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{breaker.State(u.Host), CircuitClosed}, {atomic.LoadInt32(&hits), int32(3)}})
+}
+
+func TestCircuitBreakerStaleResult(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Minute).HalfOpenProbes(2)
+	breaker.now = func() time.Time { return now }
+
+	// 关闭时放行的请求在半开后才完成, 不影响试探
+	stale, _ := breaker.allow("h")
+	breaker.record("h", 0, false)
+	now = now.Add(time.Minute)
+	probe, ok := breaker.allow("h")
+	breaker.record("h", stale, true)
+	eq(t, [][2]any{{ok, true}, {probe != 0, true}, {breaker.State("h"), CircuitHalfOpen}, {breaker.circuits["h"].probing, 1}})
+
+	// 上一次半开的试探请求在重新打开后才完成, 不影响新的半开
+	breaker.record("h", probe, false)
+	now = now.Add(time.Minute)
+	next, _ := breaker.allow("h")
+	breaker.record("h", probe, true)
+	breaker.release("h", probe)
+	eq(t, [][2]any{{breaker.State("h"), CircuitHalfOpen}, {breaker.circuits["h"].probing, 1}})
+
+	breaker.record("h", next, true)
+	eq(t, [][2]any{{breaker.State("h"), CircuitClosed}, {breaker.circuits["h"].probing, 0}})
+}
@@ -40,7 +40,7 @@ func Referer(referer string) HeaderOption {
 // Browser 浏览器
 func Browser(ctx context.Context) *Request {
 	ms := time.Millisecond
-	return New(ctx).HeaderWith(AcceptHTML, AcceptChinese).TryAt(ms*300, ms*800, ms*1500)
+	return New(ctx).HeaderWith(AcceptHTML, AcceptChinese).TryAt(ms*300, ms*800, ms*1500).ProcessWith(CheckStatus)
 }
 
 // MacEdge Mac Edge 浏览器
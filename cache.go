@@ -0,0 +1,515 @@
+package urlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderAge             = "Age"
+	HeaderDate            = "Date"
+	HeaderETag            = "ETag"
+	HeaderExpires         = "Expires"
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderIfModifiedSince = "If-Modified-Since"
+	HeaderVary            = "Vary"
+	HeaderCacheStatus     = "X-Urlx-Cache" // 缓存状态, 见 CacheHit, CacheRevalidated
+)
+
+const (
+	CacheHit         = "hit"         // 直接使用缓存
+	CacheRevalidated = "revalidated" // 验证后使用缓存
+)
+
+const (
+	cacheHeuristicFraction  = 10             // 启发式新鲜时长为 (Date - Last-Modified) / 10
+	cacheHeuristicMaxExpiry = 24 * time.Hour // 启发式新鲜时长的上限
+	cacheMaxVariants        = 16             // 同一链接最多保存的 Vary 变体数
+)
+
+// DefaultCacheMaxEntrySize 默认缓存的响应内容的最大字节数, 超过的响应不缓存
+const DefaultCacheMaxEntrySize = 4 << 20
+
+// CacheStore 缓存存储
+type CacheStore interface {
+	Get(key string) (data []byte, ok bool)
+	Set(key string, data []byte)
+	Delete(key string)
+}
+
+// Cache HTTP 私有缓存(RFC 7234), 作为请求中间件使用, 缓存的响应与实际的响应一样经过 ProcessMw 处理.
+// 只缓存 GET 请求, 支持 Cache-Control, Expires, Vary(每个变体分别缓存), 并使用 ETag 和 Last-Modified 重新验证
+type Cache struct {
+	store   CacheStore
+	maxSize int64
+	now     func() time.Time
+
+	mu sync.Mutex // 保护 Vary 变体索引的读取和修改
+}
+
+// NewCache 使用存储创建缓存
+func NewCache(store CacheStore) *Cache {
+	return &Cache{store: store, maxSize: DefaultCacheMaxEntrySize, now: time.Now}
+}
+
+// MaxEntrySize 缓存的响应内容的最大字节数, 超过的响应直接返回不缓存, 0为 DefaultCacheMaxEntrySize, 小于0不限制
+func (c *Cache) MaxEntrySize(size int64) *Cache {
+	if size == 0 {
+		size = DefaultCacheMaxEntrySize
+	}
+	c.maxSize = size
+	return c
+}
+
+// UseCache 使用缓存
+func UseCache(cache *Cache) Option {
+	return Transport(cache.Mw)
+}
+
+// CacheStatus 响应的缓存状态, 不是来自缓存则为空
+func CacheStatus(resp *http.Response) string {
+	return resp.Header.Get(HeaderCacheStatus)
+}
+
+// cacheEntry 缓存项
+type cacheEntry struct {
+	StatusCode   int               `json:"status"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	Vary         map[string]string `json:"vary,omitempty"` // Vary 指定的请求头的值
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+}
+
+// cacheVariants 同一链接的 Vary 变体
+type cacheVariants struct {
+	Vary []string `json:"vary"` // Vary 指定的请求头
+	Keys []string `json:"keys"` // 变体的键, 最近保存的在最后
+}
+
+// Mw 请求中间件
+func (c *Cache) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		key := cacheKey(req)
+		if req.Method != http.MethodGet {
+			if !isSafeMethod(req.Method) {
+				c.delete(key)
+			}
+			return next.RoundTrip(req)
+		}
+
+		reqCC := parseCacheControl(req.Header)
+		if _, ok := reqCC["no-store"]; ok {
+			return next.RoundTrip(req)
+		}
+
+		entryKey, entry := c.load(key, req)
+		if entry != nil && c.fresh(entry, reqCC) {
+			return entry.response(req, CacheHit, c.now()), nil
+		}
+
+		if _, ok := reqCC["only-if-cached"]; ok {
+			return &http.Response{
+				Status:     "504 Gateway Timeout",
+				StatusCode: http.StatusGatewayTimeout,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{},
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		}
+
+		outReq := req
+		if entry != nil && req.Header.Get(HeaderIfNoneMatch) == "" && req.Header.Get(HeaderIfModifiedSince) == "" {
+			etag, lastModified := entry.Header.Get(HeaderETag), entry.Header.Get(HeaderLastModified)
+			if etag != "" || lastModified != "" {
+				outReq = req.Clone(req.Context())
+				if etag != "" {
+					outReq.Header.Set(HeaderIfNoneMatch, etag)
+				}
+				if lastModified != "" {
+					outReq.Header.Set(HeaderIfModifiedSince, lastModified)
+				}
+			} else {
+				entry = nil
+			}
+		}
+
+		requestTime := c.now()
+		resp, err := next.RoundTrip(outReq)
+		if err != nil {
+			return nil, err
+		}
+		responseTime := c.now()
+
+		if entry != nil && outReq != req && resp.StatusCode == http.StatusNotModified {
+			drain(resp.Body)
+			entry.update(resp.Header)
+			entry.RequestTime, entry.ResponseTime = requestTime, responseTime
+			c.save(entryKey, entry)
+			return entry.response(req, CacheRevalidated, responseTime), nil
+		}
+
+		if !cacheable(resp) || c.maxSize >= 0 && resp.ContentLength > c.maxSize {
+			return resp, nil
+		}
+
+		entry = &cacheEntry{
+			StatusCode:   resp.StatusCode,
+			Header:       resp.Header.Clone(),
+			Vary:         varyValues(resp.Header, req.Header),
+			RequestTime:  requestTime,
+			ResponseTime: responseTime,
+		}
+		resp.Body = &cacheBody{ReadCloser: resp.Body, limit: c.maxSize, done: func(body []byte) {
+			entry.Body = body
+			c.put(key, entry)
+		}}
+		return resp, nil
+	})
+}
+
+// load 读取请求对应的变体, 返回缓存项的键
+func (c *Cache) load(key string, req *http.Request) (string, *cacheEntry) {
+	if variants := c.variants(key); variants != nil {
+		key = variantKey(key, variants.Vary, req.Header.Get)
+	}
+	data, ok := c.store.Get(key)
+	if !ok {
+		return key, nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.store.Delete(key)
+		return key, nil
+	}
+	for name, value := range entry.Vary {
+		if req.Header.Get(name) != value {
+			return key, nil
+		}
+	}
+	return key, &entry
+}
+
+// put 保存新的响应, 有 Vary 时按变体分别保存
+func (c *Cache) put(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	variants := c.variants(key)
+	if len(entry.Vary) == 0 {
+		if variants != nil {
+			c.remove(key)
+		}
+		c.save(key, entry)
+		return
+	}
+
+	names := make([]string, 0, len(entry.Vary))
+	for name := range entry.Vary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if variants == nil || strings.Join(variants.Vary, ",") != strings.Join(names, ",") {
+		c.remove(key)
+		variants = &cacheVariants{Vary: names}
+	}
+
+	k := variantKey(key, names, func(name string) string { return entry.Vary[name] })
+	keys := variants.Keys[:0]
+	for _, old := range variants.Keys {
+		if old != k {
+			keys = append(keys, old)
+		}
+	}
+	if keys = append(keys, k); len(keys) > cacheMaxVariants {
+		c.store.Delete(keys[0])
+		keys = keys[1:]
+	}
+	variants.Keys = keys
+
+	c.save(k, entry)
+	if data, err := json.Marshal(variants); err == nil {
+		c.store.Set(variantsKey(key), data)
+	}
+}
+
+// variants 读取链接的 Vary 变体, 没有则为空
+func (c *Cache) variants(key string) *cacheVariants {
+	data, ok := c.store.Get(variantsKey(key))
+	if !ok {
+		return nil
+	}
+	var variants cacheVariants
+	if err := json.Unmarshal(data, &variants); err != nil {
+		c.store.Delete(variantsKey(key))
+		return nil
+	}
+	return &variants
+}
+
+// delete 删除链接的缓存, 包括所有的变体
+func (c *Cache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+// remove 删除链接的缓存, 调用时需要持有 c.mu
+func (c *Cache) remove(key string) {
+	if variants := c.variants(key); variants != nil {
+		for _, k := range variants.Keys {
+			c.store.Delete(k)
+		}
+		c.store.Delete(variantsKey(key))
+	}
+	c.store.Delete(key)
+}
+
+// save 保存
+func (c *Cache) save(key string, entry *cacheEntry) {
+	if data, err := json.Marshal(entry); err == nil {
+		c.store.Set(key, data)
+	}
+}
+
+// fresh 是否新鲜, 可以不经验证直接使用
+func (c *Cache) fresh(entry *cacheEntry, reqCC cacheControl) bool {
+	if _, ok := reqCC["no-cache"]; ok {
+		return false
+	}
+	if strings.Contains(strings.ToLower(entry.Header.Get(HeaderPragma)), "no-cache") {
+		return false
+	}
+	respCC := parseCacheControl(entry.Header)
+	if _, ok := respCC["no-cache"]; ok {
+		return false
+	}
+
+	lifetime := entry.lifetime(respCC)
+	age := entry.age(c.now())
+	if maxAge, ok := reqCC.duration("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := reqCC.duration("min-fresh"); ok {
+		age += minFresh
+	}
+	return age < lifetime
+}
+
+// lifetime 新鲜时长
+func (e *cacheEntry) lifetime(respCC cacheControl) time.Duration {
+	if maxAge, ok := respCC.duration("max-age"); ok {
+		return maxAge
+	}
+
+	date, err := http.ParseTime(e.Header.Get(HeaderDate))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	if expires := e.Header.Get(HeaderExpires); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return t.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(e.Header.Get(HeaderLastModified)); err == nil && date.After(lastModified) {
+		if d := date.Sub(lastModified) / cacheHeuristicFraction; d < cacheHeuristicMaxExpiry {
+			return d
+		}
+		return cacheHeuristicMaxExpiry
+	}
+	return 0
+}
+
+// age 当前的年龄 RFC 7234 4.2.3
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(e.Header.Get(HeaderDate)); err == nil {
+		if apparentAge = e.ResponseTime.Sub(date); apparentAge < 0 {
+			apparentAge = 0
+		}
+	}
+	responseDelay := e.ResponseTime.Sub(e.RequestTime)
+	correctedAge := responseDelay
+	if seconds, err := strconv.ParseInt(e.Header.Get(HeaderAge), 10, 64); err == nil {
+		correctedAge += time.Duration(seconds) * time.Second
+	}
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// update 使用 304 响应的头更新缓存
+func (e *cacheEntry) update(header http.Header) {
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", HeaderContentType:
+			continue
+		}
+		e.Header[name] = values
+	}
+}
+
+// response 构造响应
+func (e *cacheEntry) response(req *http.Request, status string, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set(HeaderAge, strconv.FormatInt(int64(e.age(now)/time.Second), 10))
+	header.Set(HeaderCacheStatus, status)
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheBody 读取完成后保存到缓存, 未读取完就关闭或者超过 limit 则不保存
+type cacheBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	limit int64
+	done  func(body []byte)
+}
+
+func (b *cacheBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if b.done == nil {
+		return
+	}
+	if b.buf.Write(p[:n]); b.limit >= 0 && int64(b.buf.Len()) > b.limit {
+		// 超过限制, 不再缓存, 之后的内容直接读取
+		b.buf, b.done = bytes.Buffer{}, nil
+		return
+	}
+	if err == io.EOF {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return
+}
+
+// cacheable 响应是否可以缓存
+func cacheable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone,
+		http.StatusRequestURITooLong, http.StatusNotImplemented, http.StatusPermanentRedirect:
+	default:
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get(HeaderVary)) == "*" {
+		return false
+	}
+	respCC := parseCacheControl(resp.Header)
+	if _, ok := respCC["no-store"]; ok {
+		return false
+	}
+	if _, ok := respCC["no-cache"]; ok {
+		return resp.Header.Get(HeaderETag) != "" || resp.Header.Get(HeaderLastModified) != ""
+	}
+	if _, ok := respCC["max-age"]; ok {
+		return true
+	}
+	return resp.Header.Get(HeaderExpires) != "" || resp.Header.Get(HeaderLastModified) != "" || resp.Header.Get(HeaderETag) != ""
+}
+
+// varyValues Vary 指定的请求头的值
+func varyValues(respHeader, reqHeader http.Header) map[string]string {
+	var vary map[string]string
+	for _, values := range respHeader[HeaderVary] {
+		for _, name := range strings.Split(values, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				if vary == nil {
+					vary = map[string]string{}
+				}
+				vary[name] = reqHeader.Get(name)
+			}
+		}
+	}
+	return vary
+}
+
+// variantsKey 保存链接的 Vary 变体的键
+func variantsKey(key string) string {
+	return "vary " + key
+}
+
+// variantKey Vary 变体的键, 包含 Vary 指定的请求头的值
+func variantKey(key string, names []string, value func(name string) string) string {
+	var sb strings.Builder
+	sb.WriteString(key)
+	for _, name := range names {
+		sb.WriteString("\n" + name + ": " + value(name))
+	}
+	return sb.String()
+}
+
+// cacheKey 缓存的键
+func cacheKey(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	return u.String()
+}
+
+// isSafeMethod 安全的方法不会使缓存失效
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// cacheControl 解析后的 Cache-Control
+type cacheControl map[string]string
+
+// duration 获取秒数指令
+func (cc cacheControl) duration(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// parseCacheControl 解析 Cache-Control
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, values := range header[http.CanonicalHeaderKey(HeaderCacheControl)] {
+		for _, part := range strings.Split(values, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			name, value := part, ""
+			if i := strings.IndexByte(part, '='); i >= 0 {
+				name, value = part[:i], strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+			}
+			cc[strings.ToLower(strings.TrimSpace(name))] = value
+		}
+	}
+	return cc
+}
@@ -0,0 +1,125 @@
+package urlx
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryCache 内存缓存存储, 超过最大数量时淘汰最久未使用的
+type MemoryCache struct {
+	maxEntries int
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key  string
+	data []byte
+}
+
+// NewMemoryCache 创建内存缓存存储, maxEntries 最大数量, 0不限制
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get 读取
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.ll.MoveToFront(e)
+		return e.Value.(*memoryCacheItem).data, true
+	}
+	return nil, false
+}
+
+// Set 保存
+func (m *MemoryCache) Set(key string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.ll.MoveToFront(e)
+		e.Value.(*memoryCacheItem).data = data
+		return
+	}
+	m.items[key] = m.ll.PushFront(&memoryCacheItem{key: key, data: data})
+	for m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		e := m.ll.Back()
+		m.ll.Remove(e)
+		delete(m.items, e.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete 删除
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.ll.Remove(e)
+		delete(m.items, key)
+	}
+}
+
+// Len 缓存的数量
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// DiskCache 磁盘缓存存储, 每个缓存项保存为目录下的一个文件
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache 创建磁盘缓存存储
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// Get 读取
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set 保存, 先写入目录下唯一的临时文件再重命名, 并发写入同一个键时不会互相覆盖临时文件
+func (d *DiskCache) Set(key string, data []byte) {
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(d.dir, "*"+DownloadTempExt)
+	if err != nil {
+		return
+	}
+	if err = f.Chmod(0644); err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// Delete 删除
+func (d *DiskCache) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
+
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,126 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	var hits, validated int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/fresh":
+			rw.Header().Set(HeaderCacheControl, "max-age=60")
+		case "/etag":
+			rw.Header().Set(HeaderCacheControl, "no-cache")
+			rw.Header().Set(HeaderETag, `"v1"`)
+			if r.Header.Get(HeaderIfNoneMatch) == `"v1"` {
+				atomic.AddInt32(&validated, 1)
+				rw.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		_, _ = rw.Write([]byte(r.URL.Path))
+	}))
+	defer closer()
+
+	for _, store := range []CacheStore{NewMemoryCache(10), NewDiskCache(t.TempDir())} {
+		atomic.StoreInt32(&hits, 0)
+		atomic.StoreInt32(&validated, 0)
+		s := NewSession(UseCache(NewCache(store))).BaseURL(addr)
+
+		var statuses []string
+		for i := 0; i < 2; i++ {
+			for _, path := range []string{"/fresh", "/etag"} {
+				var status string
+				data, err := s.New(context.TODO()).Url(path).
+					ProcessWith(func(next Process) Process {
+						return func(resp *http.Response) error { status = CacheStatus(resp); return next(resp) }
+					}).
+					Bytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+				eq(t, [][2]any{{string(data), path}})
+				statuses = append(statuses, status)
+			}
+		}
+		eq(t, [][2]any{
+			{statuses[0] + "," + statuses[1], ","},
+			{statuses[2] + "," + statuses[3], CacheHit + "," + CacheRevalidated},
+			{atomic.LoadInt32(&hits), int32(3)},
+			{atomic.LoadInt32(&validated), int32(1)},
+		})
+	}
+}
+
+func TestCacheVaryAndSize(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		rw.Header().Set(HeaderCacheControl, "max-age=60")
+		if r.URL.Path == "/large" {
+			// 分块传输, 没有 Content-Length, 读取时才能判断大小
+			rw.(http.Flusher).Flush()
+			_, _ = rw.Write(make([]byte, 2048))
+			return
+		}
+		rw.Header().Set(HeaderVary, HeaderAcceptLanguage)
+		_, _ = rw.Write([]byte(r.Header.Get(HeaderAcceptLanguage)))
+	}))
+	defer closer()
+
+	s := NewSession(UseCache(NewCache(NewMemoryCache(10)).MaxEntrySize(1024))).BaseURL(addr)
+	get := func(path, lang string) string {
+		data, err := s.New(context.TODO()).Url(path).HeaderWith(HeaderSet(HeaderAcceptLanguage, lang)).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// 每个 Vary 变体分别缓存
+	var langs []string
+	for i := 0; i < 2; i++ {
+		langs = append(langs, get("/vary", "en"), get("/vary", "zh"))
+	}
+	eq(t, [][2]any{{strings.Join(langs, ","), "en,zh,en,zh"}, {atomic.LoadInt32(&hits), int32(2)}})
+
+	// 超过大小限制的响应不缓存
+	atomic.StoreInt32(&hits, 0)
+	for i := 0; i < 2; i++ {
+		eq(t, [][2]any{{len(get("/large", "")), 2048}})
+	}
+	eq(t, [][2]any{{atomic.LoadInt32(&hits), int32(2)}})
+}
+
+func TestCacheConcurrentVariants(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(NewDiskCache(dir))
+
+	// 同一链接的多个变体并发保存, 变体索引不丢失
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(lang string) {
+			defer wg.Done()
+			c.put("GET http://a/", &cacheEntry{StatusCode: http.StatusOK, Vary: map[string]string{HeaderAcceptLanguage: lang}})
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	files, _ := os.ReadDir(dir)
+	variants := c.variants("GET http://a/")
+	eq(t, [][2]any{{len(variants.Keys), 8}, {len(files), 9}})
+
+	c.delete("GET http://a/")
+	files, _ = os.ReadDir(dir)
+	eq(t, [][2]any{{len(files), 0}})
+}
@@ -0,0 +1,312 @@
+package urlx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrCassetteMiss 回放时没有匹配的记录
+var ErrCassetteMiss = errors.New("cassette: no matching interaction")
+
+// Redacted 脱敏后的值
+const Redacted = "REDACTED"
+
+// CassetteMode 录制回放模式
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = iota // 只回放, 没有匹配的记录返回 ErrCassetteMiss
+	CassetteRecord                     // 发送请求并录制, 覆盖已有的文件
+	CassetteAuto                       // 文件存在则回放, 否则录制
+)
+
+// Interaction 一次录制的请求和响应
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest 录制的请求
+type CassetteRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   CassetteBody `json:"body,omitempty"`
+}
+
+// CassetteResponse 录制的响应
+type CassetteResponse struct {
+	StatusCode int          `json:"status_code"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       CassetteBody `json:"body,omitempty"`
+}
+
+// CassetteBody 录制的内容, 文本直接保存, 二进制保存为 base64
+type CassetteBody []byte
+
+// MarshalJSON 实现 json.Marshaler
+func (b CassetteBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (b *CassetteBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = CassetteBody(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = raw
+	return err
+}
+
+// CassetteMatcher 判断请求与录制的请求是否匹配, 两者都经过了脱敏
+type CassetteMatcher = func(live, recorded *CassetteRequest) bool
+
+// MatchMethod 匹配请求方法
+func MatchMethod(live, recorded *CassetteRequest) bool { return live.Method == recorded.Method }
+
+// MatchURL 匹配请求地址, Query 参数的顺序不影响匹配
+func MatchURL(live, recorded *CassetteRequest) bool {
+	a, errA := url.Parse(live.URL)
+	b, errB := url.Parse(recorded.URL)
+	if errA != nil || errB != nil {
+		return live.URL == recorded.URL
+	}
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Path == b.Path && a.Query().Encode() == b.Query().Encode()
+}
+
+// MatchBody 匹配请求内容
+func MatchBody(live, recorded *CassetteRequest) bool { return bytes.Equal(live.Body, recorded.Body) }
+
+// MatchHeader 匹配指定的请求头
+func MatchHeader(names ...string) CassetteMatcher {
+	return func(live, recorded *CassetteRequest) bool {
+		for _, name := range names {
+			if live.Header.Get(name) != recorded.Header.Get(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RedactHeaders 脱敏请求和响应头
+func RedactHeaders(names ...string) func(*Interaction) {
+	return func(i *Interaction) {
+		for _, header := range []http.Header{i.Request.Header, i.Response.Header} {
+			for _, name := range names {
+				if values := header.Values(name); len(values) > 0 {
+					header.Set(name, Redacted)
+				}
+			}
+		}
+	}
+}
+
+// RedactQuery 脱敏请求地址中的 Query 参数
+func RedactQuery(names ...string) func(*Interaction) {
+	return func(i *Interaction) {
+		u, err := url.Parse(i.Request.URL)
+		if err != nil {
+			return
+		}
+		query := u.Query()
+		for _, name := range names {
+			if query.Has(name) {
+				query.Set(name, Redacted)
+			}
+		}
+		u.RawQuery = query.Encode()
+		i.Request.URL = u.String()
+	}
+}
+
+// Cassette 录制回放请求, 用于不依赖服务器的确定性测试.
+// 默认按请求方法和地址匹配, 同一请求多次录制时按顺序回放
+type Cassette struct {
+	path     string
+	mode     CassetteMode
+	matchers []CassetteMatcher
+	redacts  []func(*Interaction)
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewCassette 创建录制回放, 回放模式读取文件, 录制模式在每次录制后写入文件
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, matchers: []CassetteMatcher{MatchMethod, MatchURL}}
+	if mode == CassetteAuto {
+		if _, err := os.Stat(path); err == nil {
+			c.mode = CassetteReplay
+		} else {
+			c.mode = CassetteRecord
+		}
+	}
+
+	if c.mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file cassetteFile
+		if err = json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("cassette: %s: %w", path, err)
+		}
+		c.interactions, c.used = file.Interactions, make([]bool, len(file.Interactions))
+	}
+	return c, nil
+}
+
+// cassetteFile 文件格式
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// MatchBy 设置匹配规则, 全部匹配才使用录制的响应
+func (c *Cassette) MatchBy(matchers ...CassetteMatcher) *Cassette {
+	c.matchers = matchers
+	return c
+}
+
+// Redact 增加脱敏处理, 录制时在保存前执行, 回放时对请求执行后再匹配
+func (c *Cassette) Redact(redacts ...func(*Interaction)) *Cassette {
+	c.redacts = append(c.redacts, redacts...)
+	return c
+}
+
+// Recording 是否在录制
+func (c *Cassette) Recording() bool { return c.mode == CassetteRecord }
+
+// UseCassette 使用录制回放
+func UseCassette(c *Cassette) Option {
+	return Transport(c.Mw)
+}
+
+// Mw 请求中间件, 录制时发送请求并保存, 回放时不发送请求
+func (c *Cassette) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := readRequestBody(req)
+		if errors.Is(err, ErrBodyNotReplayable) {
+			if body, err = io.ReadAll(req.Body); err == nil {
+				req = req.Clone(req.Context())
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		live := &Interaction{Request: CassetteRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body}}
+		if c.mode == CassetteRecord {
+			return c.record(next, req, live)
+		}
+		return c.replay(req, live)
+	})
+}
+
+// replay 回放第一个未使用的匹配记录
+func (c *Cassette) replay(req *http.Request, live *Interaction) (*http.Response, error) {
+	for _, redact := range c.redacts {
+		redact(live)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, recorded := range c.interactions {
+		if c.used[i] || !c.match(&live.Request, &recorded.Request) {
+			continue
+		}
+		c.used[i] = true
+		r := recorded.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+			StatusCode:    r.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        r.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(r.Body)),
+			ContentLength: int64(len(r.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, req.Method, req.URL.Redacted())
+}
+
+// match 是否全部匹配
+func (c *Cassette) match(live, recorded *CassetteRequest) bool {
+	for _, match := range c.matchers {
+		if !match(live, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// record 发送请求, 读取响应内容并保存
+func (c *Cassette) record(next http.RoundTripper, req *http.Request, live *Interaction) (*http.Response, error) {
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	closes(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	live.Response = CassetteResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: data}
+	for _, redact := range c.redacts {
+		redact(live)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, live)
+	c.used = append(c.used, true)
+	if err = c.save(); err != nil {
+		drain(resp.Body)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save 写入文件, 先写临时文件再替换
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,63 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bin" {
+			_, _ = rw.Write([]byte{0xff, 0x00, 0xfe})
+			return
+		}
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		rw.Header().Set("X-Session", "secret")
+		_, _ = rw.Write([]byte(r.Method + " " + r.URL.Query().Get("q") + " " + string(body)))
+	}))
+
+	run := func(c *Cassette) (results []string, err error) {
+		for _, body := range []string{"a=1", "a=2"} {
+			data, err := New(context.TODO(), UseCassette(c)).Url(addr + "/search?token=t1&q=x").Method(MethodPost).
+				HeaderWith(BearerAuth("t1")).Form(strings.NewReader(body)).Bytes()
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, string(data))
+		}
+		data, err := New(context.TODO(), UseCassette(c)).Url(addr + "/bin").Bytes()
+		return append(results, string(data)), err
+	}
+	redact := []func(*Interaction){RedactHeaders(HeaderAuthorization, "X-Session"), RedactQuery("token")}
+
+	recorder, err := NewCassette(path, CassetteAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := run(recorder.Redact(redact...))
+	eq(t, [][2]any{{err, nil}, {recorder.Recording(), true}, {recorded[0], "POST x a=1"}})
+
+	data, _ := os.ReadFile(path)
+	eq(t, [][2]any{{strings.Contains(string(data), "t1"), false}, {strings.Contains(string(data), "secret"), false}})
+
+	// 回放不需要服务器, 令牌不同也能匹配
+	closer()
+	player, err := NewCassette(path, CassetteAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player.Redact(redact...).MatchBy(MatchMethod, MatchURL, MatchBody)
+	replayed, err := run(player)
+	eq(t, [][2]any{{err, nil}, {player.Recording(), false}, {strings.Join(replayed, "|"), strings.Join(recorded, "|")}})
+
+	_, err = run(player)
+	eq(t, [][2]any{{errors.Is(err, ErrCassetteMiss), true}})
+}
@@ -23,11 +23,8 @@ type ProcessMw = func(next Process) Process
 func Charset(charset string) ProcessMw {
 	getCharset := func(params map[string]string) string {
 		if charset == "" || charset == "auto" {
-			if len(params) > 0 {
-				if charset = strings.TrimSpace(params[ParamCharset]); charset != "" {
-					return charset
-				}
-			}
+			// 不能修改 charset, AutoCharset 在所有响应之间共享
+			return strings.ToLower(strings.TrimSpace(params[ParamCharset]))
 		}
 		return strings.ToLower(charset)
 	}
@@ -36,7 +33,7 @@ func Charset(charset string) ProcessMw {
 		return func(resp *http.Response) error {
 			var body = io.Reader(resp.Body)
 			mimeType, params, _ := mime.ParseMediaType(resp.Header.Get(HeaderContentType))
-			if cs := getCharset(params); cs != "" && cs != "UTF-8" {
+			if cs := getCharset(params); cs != "" && cs != "utf-8" {
 				if codec, err := htmlindex.Get(cs); err == nil && codec != unicode.UTF8 {
 					body = transform.NewReader(body, codec.NewDecoder())
 					resp.Header.Set(HeaderContentType, mimeType)
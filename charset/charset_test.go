@@ -0,0 +1,28 @@
+package urlx
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAutoCharset(t *testing.T) {
+	for _, c := range [][3]string{
+		{"text/html; charset=gbk", "\xc4\xe3\xba\xc3", "text/html"},
+		{"text/plain; charset=GB2312", "\xc4\xe3\xba\xc3", "text/plain"},
+		{"text/plain; charset=utf-8", "你好", "text/plain; charset=utf-8"},
+	} {
+		resp := &http.Response{Header: http.Header{HeaderContentType: {c[0]}}, Body: io.NopCloser(strings.NewReader(c[1]))}
+		var text string
+		err := AutoCharset(func(resp *http.Response) error {
+			data, err := io.ReadAll(resp.Body)
+			text = string(data)
+			return err
+		})(resp)
+		// 转换后去掉 charset, urlx.Response.Text 按 UTF-8 解码
+		if err != nil || text != "你好" || resp.Header.Get(HeaderContentType) != c[2] {
+			t.Fatalf("%s: got %q, %q, %v", c[0], text, resp.Header.Get(HeaderContentType), err)
+		}
+	}
+}
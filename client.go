@@ -1,17 +1,18 @@
 package urlx
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"time"
 )
 
 /* 设置客户端 */
 
-// TryAt 失败重试，等待休眠时间
+// TryAt 失败重试，等待休眠时间, 不限制单次等待时间(包括 Retry-After)
 func (c *Request) TryAt(times ...time.Duration) *Request {
-	c.tryTimes = times
-	return c
+	return c.RetryWith(RetryPolicy{MaxAttempts: len(times) + 1, Backoff: BackoffDelays(times...), MaxDelay: -1})
 }
 
 // UseClient 使用的客户端定义
@@ -32,7 +33,7 @@ func CookieEnabled(enabled ...bool) Option {
 // Jar 设置Cookie容器
 func Jar(jar http.CookieJar) Option {
 	return func(c *Request) error {
-		c.client.Jar = jar
+		c.jar, c.jarSet = jar, true
 		return nil
 	}
 }
@@ -45,15 +46,32 @@ func UseClient(client *http.Client) Option {
 	}
 }
 
-// TryIdempotent 幂等重试
-func TryIdempotent(base time.Duration, maxTimes int) Option {
-	trys := make([]time.Duration, maxTimes)
-	for i := 0; i < maxTimes; i++ {
-		trys[i] = base * (1 << i)
+// Proxy 使用代理, 支持 http, https 和 socks5, 客户端的 Transport 必须是 *http.Transport
+func (c *Request) Proxy(proxy string) *Request {
+	return c.With(ProxyURL(proxy))
+}
+
+// ProxyURL 使用代理, 为空则取消之前设置的代理
+func ProxyURL(proxy string) Option {
+	return func(r *Request) error {
+		if proxy == "" {
+			r.proxy = nil
+			return nil
+		}
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+		r.proxy = u
+		return nil
 	}
+}
+
+// TryIdempotent 幂等重试，等待时间按 base 指数增长, 不限制单次等待时间(包括 Retry-After)
+func TryIdempotent(base time.Duration, maxTimes int) Option {
 	return func(r *Request) error {
-		if len(trys) > 0 {
-			r.TryAt(trys...)
+		if maxTimes > 0 {
+			r.RetryWith(RetryPolicy{MaxAttempts: maxTimes + 1, Backoff: BackoffExponential(base, 0), MaxDelay: -1})
 		}
 		return nil
 	}
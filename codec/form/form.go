@@ -12,8 +12,9 @@ import (
 )
 
 type (
-	Process = func(resp *http.Response) error // 响应处理器
-	Body    = func() (contentType string, body io.Reader, err error)
+	Process     = func(resp *http.Response) error // 响应处理器
+	Body        = func() (contentType string, body io.Reader, err error)
+	QueryOption = func(query url.Values) error // 请求Query参数处理
 )
 
 // Decode 处理JSON响应
@@ -61,3 +62,17 @@ func Encode(in any) Body {
 		return
 	}
 }
+
+// Query 使用 go-querystring 将结构体编码为请求Query参数, 支持全部标签选项, 用于 Request.QueryWith
+func Query(in any) QueryOption {
+	return func(values url.Values) error {
+		encoded, err := query.Values(in)
+		if err != nil {
+			return err
+		}
+		for key, vs := range encoded {
+			values[key] = append(values[key], vs...)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package form
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	type Params struct {
+		Tags  []string  `url:"tags,comma"`
+		IDs   []int     `url:"id,brackets"`
+		Since time.Time `url:"since,unix"`
+	}
+
+	values := url.Values{"keep": {"1"}}
+	if err := Query(Params{Tags: []string{"x", "y"}, IDs: []int{1, 2}, Since: time.Unix(1600000000, 0)})(values); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := values.Encode(), "id%5B%5D=1&id%5B%5D=2&keep=1&since=1600000000&tags=x%2Cy"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+package html
+
+// Processor 可以处理响应的请求, 如 *urlx.Request
+type Processor interface {
+	Process(process Process) error
+}
+
+// HTML 发送请求并将 selection 选中的 HTML 解析为 T
+func HTML[T any](req Processor, selection string, options ...StructOptions) (out T, err error) {
+	var opts StructOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	err = req.Process(Struct(&out, selection, opts))
+	return
+}
@@ -0,0 +1,52 @@
+package html
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// pageProcessor 使用固定的 HTML 响应
+type pageProcessor string
+
+func (p pageProcessor) Process(process Process) error {
+	return process(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(p)))})
+}
+
+func TestHTML(t *testing.T) {
+	page := pageProcessor(`<ul id="items">
+		<li><a href="/a">A</a><span>1</span></li>
+		<li><a href="/b">B</a><span>2</span></li>
+	</ul>`)
+
+	type Item struct {
+		Name  string `select:"a"`
+		Link  string `select:"a" attr:"href"`
+		Count int    `select:"span"`
+	}
+
+	items, err := HTML[[]Item](page, "#items li")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != (Item{"A", "/a", 1}) || items[1] != (Item{"B", "/b", 2}) {
+		t.Fatalf("got %+v", items)
+	}
+
+	type List struct {
+		Names []string `css:"li a"`
+	}
+	list, err := HTML[List](page, "#items", StructOptions{SelectTag: "css"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(list.Names, ",") != "A,B" {
+		t.Fatalf("got %+v", list)
+	}
+
+	if _, err = HTML[map[string]string](page, "#items"); !errors.Is(err, ErrValueCannotAddress) {
+		t.Fatalf("want ErrValueCannotAddress, got %v", err)
+	}
+}
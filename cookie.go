@@ -2,7 +2,6 @@ package urlx
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 )
@@ -24,7 +23,7 @@ func ReadCookie(read func(cookies []*http.Cookie) error) ProcessMw {
 	}
 }
 
-// CookieAddString 添加Cookie到请求
+// CookieAddString 添加Cookie到请求, 原样添加, 不检查无效的字节
 func CookieAddString(cookies ...string) HeaderOption {
 	return func(headers http.Header) {
 		for _, s := range cookies {
@@ -39,17 +38,28 @@ func CookieAddString(cookies ...string) HeaderOption {
 	}
 }
 
-// CookieAdd 添加Cookie到请求
+// Cookie 添加Cookie到请求, 与 CookieAdd 相同, 丢弃无效的字节时通过 Logger 记录 LogWarn 事件
+func (c *Request) Cookie(cookies ...*http.Cookie) *Request {
+	c.cookies = append(c.cookies, cookies...)
+	return c
+}
+
+// CookieAdd 添加Cookie到请求, 无效的字节被静默丢弃(HeaderOption 无法访问请求的 Logger), 需要记录警告时使用 Request.Cookie
 func CookieAdd(cookies ...*http.Cookie) HeaderOption {
 	return func(headers http.Header) {
-		for _, cookie := range cookies {
-			if cookie != nil {
-				s := fmt.Sprintf("%s=%s", sanitizeCookieName(cookie.Name), sanitizeCookieValue(cookie.Value))
-				if c := headers.Get(HeaderRequestCookie); c != "" {
-					headers.Set(HeaderRequestCookie, c+"; "+s)
-				} else {
-					headers.Set(HeaderRequestCookie, s)
-				}
+		addCookies(headers, cookies, nil)
+	}
+}
+
+// addCookies 添加Cookie到请求头, 丢弃无效的字节时调用 warn
+func addCookies(headers http.Header, cookies []*http.Cookie, warn func(err error)) {
+	for _, cookie := range cookies {
+		if cookie != nil {
+			s := fmt.Sprintf("%s=%s", sanitizeCookieName(cookie.Name), sanitizeCookieValue(cookie.Value, warn))
+			if c := headers.Get(HeaderRequestCookie); c != "" {
+				headers.Set(HeaderRequestCookie, c+"; "+s)
+			} else {
+				headers.Set(HeaderRequestCookie, s)
 			}
 		}
 	}
@@ -61,8 +71,8 @@ func sanitizeCookieName(n string) string {
 	return cookieNameSanitizer.Replace(n)
 }
 
-func sanitizeCookieValue(v string) string {
-	v = sanitizeOrWarn("Cookie.Value", validCookieValueByte, v)
+func sanitizeCookieValue(v string, warn func(err error)) string {
+	v = sanitizeOrWarn("Cookie.Value", validCookieValueByte, v, warn)
 	if len(v) == 0 {
 		return v
 	}
@@ -76,13 +86,16 @@ func validCookieValueByte(b byte) bool {
 	return 0x20 <= b && b < 0x7f && b != '"' && b != ';' && b != '\\'
 }
 
-func sanitizeOrWarn(fieldName string, valid func(byte) bool, v string) string {
+// sanitizeOrWarn 丢弃无效的字节, warn 不为空时报告第一个无效的字节
+func sanitizeOrWarn(fieldName string, valid func(byte) bool, v string, warn func(err error)) string {
 	ok := true
 	for i := 0; i < len(v); i++ {
 		if valid(v[i]) {
 			continue
 		}
-		log.Printf("net/http: invalid byte %q in %s; dropping invalid bytes", v[i], fieldName)
+		if warn != nil {
+			warn(fmt.Errorf("net/http: invalid byte %q in %s; dropping invalid bytes", v[i], fieldName))
+		}
 		ok = false
 		break
 	}
@@ -0,0 +1,360 @@
+package urlx
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+/* curl 命令导出和导入 */
+
+// Curl 导出为等效的 curl 命令, 包括方法, 链接, 请求头, Cookie容器中的 Cookie, 请求内容和代理.
+// 只能读取一次的请求内容导出后无法再发送
+func (c *Request) Curl() (string, error) {
+	r := c.Clone()
+	if err := r.prepare(); err != nil {
+		return "", err
+	}
+	req, release, err := r.build()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	args := []string{"curl"}
+	if req.Method != http.MethodGet {
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	if r.client.Jar != nil {
+		var cookies []string
+		for _, cookie := range r.client.Jar.Cookies(req.URL) {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+		if len(cookies) > 0 {
+			args = append(args, "-b", shellQuote(strings.Join(cookies, "; ")))
+		}
+	}
+
+	data, err := readRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(data)))
+	}
+
+	if r.proxy != nil {
+		args = append(args, "-x", shellQuote(r.proxy.String()))
+	}
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote 使用单引号转义, 不需要转义的原样返回
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ErrCurlUnsupported 不支持的 curl 参数
+var ErrCurlUnsupported = errors.New("curl: unsupported option")
+
+// curlSwitches 不需要值的参数, 除 -G 和 -I 外都不影响请求内容, 解析时忽略
+var curlSwitches = map[string]bool{
+	"-G": true, "--get": true, "-I": true, "--head": true,
+	"--compressed": true, "-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-L": true, "--location": true, "-i": true, "--include": true, "-v": true, "--verbose": true,
+	"-k": true, "--insecure": true, "-g": true, "--globoff": true, "-N": true, "--no-buffer": true,
+	"-f": true, "--fail": true, "--fail-with-body": true, "--http1.0": true, "--http1.1": true,
+	"--http2": true, "--http2-prior-knowledge": true, "--http3": true,
+}
+
+// ParseCurl 解析 curl 命令(如浏览器开发者工具中复制的 cURL (bash)), 返回等效的请求.
+// 支持 -X -H -d --data-raw --data-binary --data-urlencode --json -b -u -A -e -x -G -I 等常用参数, 忽略 -k --compressed 等不影响请求的参数
+func ParseCurl(ctx context.Context, command string) (*Request, error) {
+	words, err := shellSplit(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 || words[0] != "curl" {
+		return nil, errors.New("curl: not a curl command")
+	}
+	words = curlArgs(words[1:])
+
+	var (
+		method, rawURL, proxy string
+		headers               = http.Header{}
+		data                  []string
+		contentType           string
+		cookies               []string
+		get, head             bool
+	)
+
+	for i := 0; i < len(words); i++ {
+		flag := words[i]
+		if !strings.HasPrefix(flag, "-") {
+			rawURL = flag
+			continue
+		}
+		if curlSwitches[flag] {
+			switch flag {
+			case "-G", "--get":
+				get = true
+			case "-I", "--head":
+				head = true
+			}
+			continue
+		}
+
+		if i+1 >= len(words) {
+			return nil, fmt.Errorf("curl: option %s requires a value", flag)
+		}
+		i++
+		value := words[i]
+
+		switch flag {
+		case "-X", "--request":
+			method = value
+		case "--url":
+			rawURL = value
+		case "-H", "--header":
+			name, v, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, fmt.Errorf("curl: invalid header %q", value)
+			}
+			headers.Add(strings.TrimSpace(name), strings.TrimSpace(v))
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if strings.HasPrefix(value, "@") && flag != "--data-raw" {
+				return nil, fmt.Errorf("%w: reading data from file %s", ErrCurlUnsupported, value)
+			}
+			data = append(data, value)
+			contentType = "application/x-www-form-urlencoded"
+		case "--data-urlencode":
+			name, v, ok := strings.Cut(value, "=")
+			if ok {
+				value = url.QueryEscape(v)
+				if name != "" {
+					value = name + "=" + value
+				}
+			} else {
+				value = url.QueryEscape(value)
+			}
+			data = append(data, value)
+			contentType = "application/x-www-form-urlencoded"
+		case "--json":
+			data = append(data, value)
+			contentType = "application/json"
+			if headers.Get("Accept") == "" {
+				headers.Set("Accept", "application/json")
+			}
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("%w: reading cookies from file %s", ErrCurlUnsupported, value)
+			}
+			cookies = append(cookies, value)
+		case "-u", "--user":
+			headers.Set(HeaderAuthorization, "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-A", "--user-agent":
+			headers.Set("User-Agent", value)
+		case "-e", "--referer":
+			headers.Set("Referer", value)
+		case "-x", "--proxy":
+			proxy = value
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrCurlUnsupported, flag)
+		}
+	}
+
+	if rawURL == "" {
+		return nil, errors.New("curl: no url")
+	}
+	// 多个 -b 和 Cookie 请求头合并为一个
+	if cookies = append(headers.Values("Cookie"), cookies...); len(cookies) > 0 {
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	r := New(ctx).Url(rawURL)
+	body := strings.Join(data, "&")
+	switch {
+	case head:
+		r.Method(MethodHead)
+	case get && len(data) > 0:
+		r.Query(body)
+	case len(data) > 0:
+		r.Method(MethodPost).Body(func() (string, io.Reader, error) { return contentType, strings.NewReader(body), nil })
+	}
+	if method != "" {
+		r.Method(method)
+	}
+	if len(headers) > 0 {
+		r.HeaderWith(func(h http.Header) {
+			for name, values := range headers {
+				h[name] = append([]string(nil), values...)
+			}
+		})
+	}
+	if proxy != "" {
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		r.Proxy(proxy)
+	}
+	return r, nil
+}
+
+// curlArgs 拆分合并的短参数, 如 -sSL 拆分为 -s -S -L, -XPOST 拆分为 -X POST
+func curlArgs(words []string) []string {
+	args := make([]string, 0, len(words))
+	for _, word := range words {
+		if len(word) <= 2 || word[0] != '-' || word[1] == '-' {
+			args = append(args, word)
+			continue
+		}
+		for i := 1; i < len(word); i++ {
+			flag := "-" + word[i:i+1]
+			args = append(args, flag)
+			if !curlSwitches[flag] {
+				if i+1 < len(word) {
+					args = append(args, word[i+1:])
+				}
+				break
+			}
+		}
+	}
+	return args
+}
+
+// shellSplit 按 shell 规则拆分命令行, 支持单引号, 双引号, $'...', 反斜杠转义和续行
+func shellSplit(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(s)
+		escapes = map[rune]string{'n': "\n", 't': "\t", 'r': "\r", '\\': "\\", '\'': "'", '"': "\"", '0': "\x00"}
+	)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i, inWord = end, true
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					if e, ok := escapes[runes[i]]; ok {
+						word.WriteString(e)
+					} else if runes[i] == 'x' || runes[i] == 'u' {
+						n := 2
+						if runes[i] == 'u' {
+							n = 4
+						}
+						var code rune
+						for j := 0; j < n && i+1 < len(runes) && isHex(runes[i+1]); j++ {
+							i++
+							code = code*16 + hexValue(runes[i])
+						}
+						word.WriteRune(code)
+					} else {
+						word.WriteRune('\\')
+						word.WriteRune(runes[i])
+					}
+					continue
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func isHex(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+func hexValue(r rune) rune {
+	switch {
+	case r >= 'a':
+		return r - 'a' + 10
+	case r >= 'A':
+		return r - 'A' + 10
+	}
+	return r - '0'
+}
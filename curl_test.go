@@ -0,0 +1,77 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCurl(t *testing.T) {
+	// 同时作为代理, 代理请求的 Host 为目标地址
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		cookie, _ := r.Cookie("sid")
+		_, _ = fmt.Fprintf(rw, "%s %s %s|%s|%s|%s|%s", r.Method, r.Host, r.URL.RequestURI(), r.Header.Get("X-Name"), r.Header.Get(HeaderContentType), cookie, body)
+	}))
+	defer closer()
+
+	jar, _ := cookiejar.New(nil)
+	target, _ := url.Parse("http://example.test/")
+	jar.SetCookies(target, []*http.Cookie{{Name: "sid", Value: "s1"}})
+
+	r := New(context.TODO(), Jar(jar), ProxyURL(addr)).Url("http://example.test/api").QuerySet("q", "a b").
+		Method(MethodPut).HeaderWith(HeaderSet("X-Name", "it's")).Form(strings.NewReader("k=v&x='y'"))
+	command, err := r.Curl()
+	eq(t, [][2]any{{err, nil}, {command, `curl -X PUT 'http://example.test/api?q=a+b' -H 'Content-Type: application/x-www-form-urlencoded; charset=utf-8' ` +
+		`-H 'X-Name: it'\''s' -b sid=s1 --data-binary 'k=v&x='\''y'\''' -x ` + addr}})
+
+	want, err := r.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseCurl(context.TODO(), command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parsed.Bytes()
+	eq(t, [][2]any{{err, nil}, {string(got), string(want)}, {strings.HasPrefix(string(got), "PUT example.test /api?q=a+b|it's|"), true}})
+}
+
+func TestParseCurl(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, pass, _ := r.BasicAuth()
+		_, _ = fmt.Fprintf(rw, "%s %s|%s|%s:%s|%s|%s", r.Method, r.URL.RequestURI(), r.Header.Get(HeaderContentType), user, pass, r.Header.Get("Cookie"), body)
+	}))
+	defer closer()
+
+	for _, c := range []struct{ command, want string }{
+		{
+			"curl '" + addr + "/a?x=1' \\\n  -H 'content-type: application/json' \\\n  -H $'cookie: a=1; b=\\'2\\'' \\\n  --data-raw $'{\"n\":\"\\u4e2d\\n\"}' \\\n  --compressed",
+			"POST /a?x=1|application/json|:|a=1; b='2'|{\"n\":\"中\n\"}",
+		},
+		{"curl -sSL -XPATCH -u user:pw \"" + addr + "/b\" -d a=1 --data-urlencode 'c=d e'", "PATCH /b|application/x-www-form-urlencoded|user:pw||a=1&c=d+e"},
+		{"curl -G " + addr + "/c -d a=1 -d b=2", "GET /c?a=1&b=2||:||"},
+		{"curl --json '{}' " + addr, "POST /|application/json|:||{}"},
+		// 忽略不影响请求的参数, 多个 Cookie 合并为一个请求头
+		{"curl -k --insecure --http2 -b a=1 -b 'b=2' -H 'Cookie: c=3' " + addr + "/d", "GET /d||:|c=3; a=1; b=2|"},
+	} {
+		r, err := ParseCurl(context.TODO(), c.command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := r.Bytes()
+		eq(t, [][2]any{{err, nil}, {string(data), c.want}})
+	}
+
+	_, err := ParseCurl(context.TODO(), "curl -d @file.json "+addr)
+	eq(t, [][2]any{{errors.Is(err, ErrCurlUnsupported), true}})
+	_, err = ParseCurl(context.TODO(), "curl '"+addr)
+	eq(t, [][2]any{{err != nil, true}})
+}
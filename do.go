@@ -0,0 +1,156 @@
+package urlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// DefaultResponseLimit Do 默认最多缓存的响应内容字节数
+const DefaultResponseLimit = 32 << 20
+
+// ErrResponseTooLarge 响应内容超过了缓存的限制
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// Do 发送请求并缓存响应内容, 返回的 Response 可以多次读取和解码.
+// limit 最多缓存的字节数, 默认 DefaultResponseLimit, 小于0不限制, 超过返回 ErrResponseTooLarge.
+// gbk, gb2312 等编码的响应使用 ProcessWith(charset.AutoCharset) 在缓存前转换为 UTF-8
+func (c *Request) Do(limit ...int64) (*Response, error) {
+	max := int64(DefaultResponseLimit)
+	if len(limit) > 0 && limit[0] != 0 {
+		max = limit[0]
+	}
+
+	var r *Response
+	err := c.Process(func(resp *http.Response) error {
+		data, truncated, err := readLimit(resp.Body, max)
+		if err != nil {
+			return err
+		}
+		if truncated {
+			return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, max)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		r = &Response{raw: resp, body: data}
+		return nil
+	})
+	return r, err
+}
+
+// Response 缓存了内容的响应
+type Response struct {
+	raw  *http.Response
+	body []byte
+}
+
+// Raw 原始响应, 响应内容已被读取, Body 为缓存内容的副本
+func (r *Response) Raw() *http.Response { return r.raw }
+
+// Status 状态码
+func (r *Response) Status() int { return r.raw.StatusCode }
+
+// Header 响应头
+func (r *Response) Header() http.Header { return r.raw.Header }
+
+// URL 最终地址, 有跳转时为跳转后的地址
+func (r *Response) URL() *url.URL {
+	if r.raw.Request == nil {
+		return nil
+	}
+	return r.raw.Request.URL
+}
+
+// Bytes 响应内容
+func (r *Response) Bytes() []byte { return r.body }
+
+// Reader 读取响应内容, 每次返回新的 Reader
+func (r *Response) Reader() io.Reader { return bytes.NewReader(r.body) }
+
+// Peek 响应内容的前 n 个字节
+func (r *Response) Peek(n int) []byte {
+	if n > len(r.body) {
+		n = len(r.body)
+	}
+	return r.body[:n]
+}
+
+// ContentType 内容类型, 没有 Content-Type 时根据内容判断
+func (r *Response) ContentType() string {
+	if contentType := r.raw.Header.Get(HeaderContentType); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(r.body)
+}
+
+// Text 响应内容的文本, 按 Content-Type 中的 charset 或 BOM 解码, 没有 BOM 的 utf-16 按大端序(RFC 2781).
+// 只支持 UTF-8, UTF-16 和 ISO-8859-1, 其他编码(如 gbk, gb2312)返回错误,
+// 需要使用 ProcessWith(charset.AutoCharset) 预先转换, 转换后的 Content-Type 不再包含 charset
+func (r *Response) Text() (string, error) {
+	_, params, _ := mime.ParseMediaType(r.raw.Header.Get(HeaderContentType))
+	return decodeText(r.body, params["charset"])
+}
+
+// JSON 解码 JSON
+func (r *Response) JSON(out interface{}) error {
+	return json.Unmarshal(r.body, out)
+}
+
+// XML 解码 XML
+func (r *Response) XML(out interface{}) error {
+	return xml.Unmarshal(r.body, out)
+}
+
+// Save 保存响应内容到文件
+func (r *Response) Save(path string) error {
+	return os.WriteFile(path, r.body, 0o644)
+}
+
+// decodeText 使用标准库解码文本, BOM 优先于 charset
+func decodeText(data []byte, charset string) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return decodeUTF16(data[2:], true), nil
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return decodeUTF16(data[2:], false), nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return string(data), nil
+	case "utf-16", "utf-16be":
+		return decodeUTF16(data, true), nil
+	case "utf-16le":
+		return decodeUTF16(data, false), nil
+	case "iso-8859-1", "latin1", "l1":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	}
+	return "", fmt.Errorf("unsupported charset %q, use charset.AutoCharset", charset)
+}
+
+// decodeUTF16 解码 UTF-16
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
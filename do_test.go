@@ -0,0 +1,90 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			rw.Header().Set(HeaderContentType, "application/json")
+			_, _ = rw.Write([]byte(`{"name":"x"}`))
+		case "/latin1":
+			rw.Header().Set(HeaderContentType, "text/plain; charset=ISO-8859-1")
+			_, _ = rw.Write([]byte{'c', 'a', 'f', 0xe9})
+		case "/utf16":
+			rw.Header()[HeaderContentType] = nil
+			_, _ = rw.Write([]byte{0xff, 0xfe, 'h', 0, 'i', 0})
+		case "/utf16be":
+			rw.Header().Set(HeaderContentType, "text/plain; charset=utf-16")
+			_, _ = rw.Write([]byte{0, 'h', 0, 'i'})
+		case "/gbk":
+			rw.Header().Set(HeaderContentType, "text/html; charset=gbk")
+			_, _ = rw.Write([]byte{0xc4, 0xe3})
+		default:
+			_, _ = rw.Write([]byte(strings.Repeat("a", 100)))
+		}
+	}))
+	defer func() {
+		// 提前关闭的响应可能留下未使用的连接, 关闭后服务器才能立即停止
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		closer()
+	}()
+
+	resp, err := New(context.TODO()).Url(addr + "/json").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v1, v2 struct{ Name string }
+	text, _ := resp.Text()
+	path := filepath.Join(t.TempDir(), "out.json")
+	eq(t, [][2]any{{resp.Status(), http.StatusOK}, {resp.Header().Get(HeaderContentType), "application/json"}, {resp.URL().Path, "/json"}})
+	eq(t, [][2]any{{resp.JSON(&v1), nil}, {resp.JSON(&v2), nil}, {v2.Name, "x"}, {text, `{"name":"x"}`}, {string(resp.Peek(2)), `{"`}})
+	if err = resp.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	eq(t, [][2]any{{string(data), `{"name":"x"}`}})
+
+	// 没有 BOM 的 utf-16 按大端序
+	for _, c := range [][3]string{{"/latin1", "café", "text/plain; charset=ISO-8859-1"}, {"/utf16", "hi", "text/plain; charset=utf-16le"}, {"/utf16be", "hi", "text/plain; charset=utf-16"}} {
+		resp, err = New(context.TODO()).Url(addr + c[0]).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err = resp.Text()
+		eq(t, [][2]any{{err, nil}, {text, c[1]}, {resp.ContentType(), c[2]}})
+	}
+
+	resp, _ = New(context.TODO()).Url(addr + "/gbk").Do()
+	_, err = resp.Text()
+	eq(t, [][2]any{{err != nil, true}})
+
+	// 与 charset.AutoCharset 一样在缓存前转换为 UTF-8 并去掉 Content-Type 中的 charset 后可以解码
+	gbk := func(next Process) Process {
+		return func(resp *http.Response) error {
+			if strings.HasSuffix(resp.Header.Get(HeaderContentType), "charset=gbk") {
+				closes(resp.Body)
+				resp.Header.Set(HeaderContentType, "text/html")
+				resp.Body = io.NopCloser(strings.NewReader("你"))
+			}
+			return next(resp)
+		}
+	}
+	resp, _ = New(context.TODO()).Url(addr + "/gbk").ProcessWith(gbk).Do()
+	text, err = resp.Text()
+	eq(t, [][2]any{{err, nil}, {text, "你"}})
+
+	_, err = New(context.TODO()).Url(addr).Do(10)
+	eq(t, [][2]any{{errors.Is(err, ErrResponseTooLarge), true}})
+	resp, err = New(context.TODO()).Url(addr).Do(-1)
+	eq(t, [][2]any{{err, nil}, {len(resp.Bytes()), 100}})
+}
@@ -2,9 +2,19 @@ package urlx
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -12,27 +22,406 @@ var (
 	dumpLine = append(bytes.Repeat([]byte("-"), 70), dumpBR...)
 )
 
+// DefaultDumpBodyLimit 默认最多记录的请求和响应内容字节数
+const DefaultDumpBodyLimit = 64 << 10
+
+// DefaultRedactHeaders 常见的敏感请求头和响应头
+var DefaultRedactHeaders = []string{HeaderAuthorization, "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", HeaderAmzSecurityToken}
+
+// DumpConfig 打印请求和响应的设置
+type DumpConfig struct {
+	RequestBody   bool     // 记录请求内容
+	ResponseBody  bool     // 记录处理器读取的响应内容, 不预读, 读满 BodyLimit 字节, 读完, 关闭或处理结束时打印
+	BodyLimit     int64    // 内容最多记录的字节数, 0为 DefaultDumpBodyLimit, 小于0不限制
+	JSONLines     bool     // 每次请求输出一行 JSON(DumpRecord), 否则输出文本
+	RedactHeaders []string // 脱敏的请求头和响应头, 如 DefaultRedactHeaders
+	RedactQuery   []string // 脱敏的 Query 参数, 如 token, access_token
+	RedactFields  []string // 脱敏的 JSON 字段(任意层级)和表单字段, 如 password
+	MaxFiles      int      // DumpFiles 最多保留的文件数, 超出时删除最早的, 0不限制
+}
+
+// DumpRecord JSON 格式的请求记录
+type DumpRecord struct {
+	Time                  time.Time   `json:"time"`
+	Method                string      `json:"method"`
+	URL                   string      `json:"url"`
+	RequestHeader         http.Header `json:"request_header,omitempty"`
+	RequestBody           string      `json:"request_body,omitempty"`
+	RequestBodyTruncated  bool        `json:"request_body_truncated,omitempty"`
+	Proto                 string      `json:"proto"`
+	Status                int         `json:"status"`
+	ResponseHeader        http.Header `json:"response_header,omitempty"`
+	ResponseBody          string      `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool        `json:"response_body_truncated,omitempty"`
+	Error                 string      `json:"error,omitempty"` // 读取内容的错误
+}
+
+// Dump 打印请求和响应, 内容最多记录 DefaultDumpBodyLimit 字节
 func Dump(w io.Writer, reqBody, respBody bool) ProcessMw {
+	return DumpWith(w, DumpConfig{RequestBody: reqBody, ResponseBody: respBody})
+}
+
+// DumpWith 按设置打印请求和响应, 并发的请求不会交错输出, 写入失败时返回错误
+func DumpWith(w io.Writer, config DumpConfig) ProcessMw {
+	var mu sync.Mutex
+	return dumpTo(config, func(*http.Response) (io.Writer, func() error, error) {
+		mu.Lock()
+		return w, func() error { mu.Unlock(); return nil }, nil
+	})
+}
+
+// DumpFiles 每次请求写入 dir 中的一个文件, 用于事后排查, 文件名包含时间, 序号, 方法和主机.
+// config.MaxFiles 大于0时只保留最近的文件, 超出时删除最早的
+func DumpFiles(dir string, config DumpConfig) ProcessMw {
+	var (
+		seq int64
+		mu  sync.Mutex
+	)
+	ext := ".txt"
+	if config.JSONLines {
+		ext = ".json"
+	}
+	return dumpTo(config, func(resp *http.Response) (io.Writer, func() error, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, nil, err
+		}
+		name := fmt.Sprintf("%s-%04d-%s-%s%s", time.Now().Format("20060102-150405.000"), atomic.AddInt64(&seq, 1),
+			resp.Request.Method, dumpFileName.ReplaceAllString(resp.Request.URL.Host, "_"), ext)
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.MaxFiles > 0 {
+			mu.Lock()
+			err = removeOldDumps(dir, config.MaxFiles)
+			mu.Unlock()
+			if err != nil {
+				_ = f.Close()
+				return nil, nil, err
+			}
+		}
+		return f, f.Close, nil
+	})
+}
+
+var (
+	dumpFileName    = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	dumpFilePattern = regexp.MustCompile(`^\d{8}-\d{6}\.\d{3}-\d{4,}-.+\.(txt|json)$`)
+)
+
+// removeOldDumps 只保留目录中最近的 keep 个打印文件, 文件名以时间开头, 按名称排序即按时间排序
+func removeOldDumps(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && dumpFilePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		if err = os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumper 预先处理的打印设置
+type dumper struct {
+	DumpConfig
+	fields   map[string]bool  // 小写的脱敏字段
+	patterns []*regexp.Regexp // 内容无法解析时按文本脱敏字段
+}
+
+// newDumper 设置默认值并编译脱敏规则
+func newDumper(config DumpConfig) *dumper {
+	if config.BodyLimit == 0 {
+		config.BodyLimit = DefaultDumpBodyLimit
+	}
+	d := &dumper{DumpConfig: config, fields: make(map[string]bool, len(config.RedactFields))}
+	for _, field := range config.RedactFields {
+		d.fields[strings.ToLower(field)] = true
+		d.patterns = append(d.patterns, regexp.MustCompile(`(?i)("`+regexp.QuoteMeta(field)+`"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`))
+	}
+	return d
+}
+
+// dumpTo 打印到 open 返回的输出, 打印完成后调用 done.
+// 记录响应内容时, 在处理器读取时记录, 读满 BodyLimit 字节, 读完, 关闭或处理结束时打印, 不会等待流式响应
+func dumpTo(config DumpConfig, open func(resp *http.Response) (w io.Writer, done func() error, err error)) ProcessMw {
+	d := newDumper(config)
 	return func(next Process) Process {
 		return func(resp *http.Response) error {
-			w.Write(dumpLine)
-			reqDump, err := httputil.DumpRequest(resp.Request, reqBody)
-			if err != nil {
-				w.Write([]byte(err.Error()))
+			record := d.record(resp)
+
+			var err error
+			write := func() { err = d.write(record, resp, open) }
+			var body *dumpBody
+			if d.ResponseBody && resp.Body != nil {
+				body = &dumpBody{ReadCloser: resp.Body, limit: d.BodyLimit, done: func(body *dumpBody) {
+					d.recordBody(record, body)
+					write()
+				}}
+				resp.Body = body
 			} else {
-				w.Write(reqDump)
+				write()
+			}
+
+			nextErr := next(resp)
+			if body != nil {
+				body.finish()
+			}
+			if nextErr != nil {
+				return nextErr
 			}
-			w.Write(dumpBR)
-			w.Write(dumpLine)
-			resDump, _ := httputil.DumpResponse(resp, respBody)
 			if err != nil {
-				w.Write([]byte(err.Error()))
+				return fmt.Errorf("dump: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// write 格式化记录并写入 open 返回的输出
+func (d *dumper) write(record *DumpRecord, resp *http.Response, open func(resp *http.Response) (w io.Writer, done func() error, err error)) error {
+	var data []byte
+	if d.JSONLines {
+		data, _ = json.Marshal(record)
+		data = append(data, '\n')
+	} else {
+		data = record.text()
+	}
+
+	w, done, err := open(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if doneErr := done(); err == nil {
+		err = doneErr
+	}
+	return err
+}
+
+// record 读取并脱敏请求, 脱敏响应头
+func (d *dumper) record(resp *http.Response) *DumpRecord {
+	config := d.DumpConfig
+	req := resp.Request
+	record := &DumpRecord{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            config.redactURL(req.URL),
+		RequestHeader:  config.redactHeader(req.Header),
+		Proto:          resp.Proto,
+		Status:         resp.StatusCode,
+		ResponseHeader: config.redactHeader(resp.Header),
+	}
+	var errs []string
+
+	if config.RequestBody && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			errs = append(errs, "request body: "+ErrBodyNotReplayable.Error())
+		} else if body, err := req.GetBody(); err != nil {
+			errs = append(errs, "request body: "+err.Error())
+		} else {
+			data, truncated, err := readLimit(body, config.BodyLimit)
+			closes(body)
+			if err != nil {
+				errs = append(errs, "request body: "+err.Error())
+			}
+			if truncated {
+				data = data[:config.BodyLimit]
+			}
+			record.RequestBody, record.RequestBodyTruncated = d.redactBody(data, req.Header.Get(HeaderContentType)), truncated
+		}
+	}
+
+	record.Error = strings.Join(errs, "; ")
+	return record
+}
+
+// recordBody 脱敏处理器读取的响应内容, 没有读完的内容视为被截断
+func (d *dumper) recordBody(record *DumpRecord, body *dumpBody) {
+	data, truncated := body.buf.Bytes(), !body.eof
+	if d.BodyLimit >= 0 && int64(len(data)) > d.BodyLimit {
+		data, truncated = data[:d.BodyLimit], true
+	}
+	record.ResponseBody, record.ResponseBodyTruncated = d.redactBody(data, record.ResponseHeader.Get(HeaderContentType)), truncated
+	if body.err != nil {
+		if record.Error != "" {
+			record.Error += "; "
+		}
+		record.Error += "response body: " + body.err.Error()
+	}
+}
+
+// dumpBody 处理器读取时记录响应内容, 读满 limit 字节, 读完, 出错或关闭时调用 done
+type dumpBody struct {
+	io.ReadCloser
+	limit int64
+	buf   bytes.Buffer
+	eof   bool
+	err   error
+	once  sync.Once
+	done  func(body *dumpBody)
+}
+
+func (b *dumpBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if n > 0 {
+		// 多记录一个字节用于判断是否超出
+		data := p[:n]
+		if remain := b.limit + 1 - int64(b.buf.Len()); b.limit >= 0 && int64(len(data)) > remain {
+			data = data[:remain]
+		}
+		b.buf.Write(data)
+	}
+	switch {
+	case err == io.EOF:
+		b.eof = true
+		b.finish()
+	case err != nil:
+		b.err = err
+		b.finish()
+	case b.limit >= 0 && int64(b.buf.Len()) > b.limit:
+		b.finish()
+	}
+	return
+}
+
+func (b *dumpBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+// finish 只调用一次 done
+func (b *dumpBody) finish() {
+	b.once.Do(func() { b.done(b) })
+}
+
+// readLimit 最多读取 limit+1 字节以判断是否超出, 小于0不限制
+func readLimit(r io.Reader, limit int64) (data []byte, truncated bool, err error) {
+	if limit < 0 {
+		data, err = io.ReadAll(r)
+		return data, false, err
+	}
+	data, err = io.ReadAll(io.LimitReader(r, limit+1))
+	return data, int64(len(data)) > limit, err
+}
+
+// text 文本格式
+func (r *DumpRecord) text() []byte {
+	var buf bytes.Buffer
+	buf.Write(dumpLine)
+	fmt.Fprintf(&buf, "%s %s\n", r.Method, r.URL)
+	_ = r.RequestHeader.Write(&buf)
+	writeDumpBody(&buf, r.RequestBody, r.RequestBodyTruncated)
+	buf.Write(dumpLine)
+	fmt.Fprintf(&buf, "%s %d %s\n", r.Proto, r.Status, http.StatusText(r.Status))
+	_ = r.ResponseHeader.Write(&buf)
+	writeDumpBody(&buf, r.ResponseBody, r.ResponseBodyTruncated)
+	if r.Error != "" {
+		fmt.Fprintf(&buf, "# error: %s\n", r.Error)
+	}
+	buf.Write(dumpLine)
+	return buf.Bytes()
+}
+
+func writeDumpBody(buf *bytes.Buffer, body string, truncated bool) {
+	buf.Write(dumpBR)
+	if body != "" {
+		buf.WriteString(body)
+		if truncated {
+			buf.WriteString("...(truncated)")
+		}
+		buf.Write(dumpBR)
+	}
+}
+
+// redactHeader 复制并脱敏请求头
+func (config DumpConfig) redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range config.RedactHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			header.Set(name, Redacted)
+		}
+	}
+	return header
+}
+
+// redactURL 脱敏 Query 参数和密码
+func (config DumpConfig) redactURL(u *url.URL) string {
+	if len(config.RedactQuery) == 0 {
+		return u.Redacted()
+	}
+	c := *u
+	query := c.Query()
+	for _, name := range config.RedactQuery {
+		if query.Has(name) {
+			query.Set(name, Redacted)
+		}
+	}
+	c.RawQuery = query.Encode()
+	return c.Redacted()
+}
+
+// redactBody 脱敏 JSON 和表单字段, 内容被截断无法解析时按文本替换
+func (d *dumper) redactBody(data []byte, contentType string) string {
+	if len(d.fields) == 0 || len(data) == 0 {
+		return string(data)
+	}
+	fields := d.fields
+
+	if strings.Contains(contentType, "x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(data)); err == nil {
+			for name := range values {
+				if fields[strings.ToLower(name)] {
+					values.Set(name, Redacted)
+				}
+			}
+			return values.Encode()
+		}
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err == nil {
+		if redacted, err := json.Marshal(redactJSON(v, fields)); err == nil {
+			return string(redacted)
+		}
+	}
+
+	text := string(data)
+	for _, pattern := range d.patterns {
+		text = pattern.ReplaceAllString(text, `$1"`+Redacted+`"`)
+	}
+	return text
+}
+
+// redactJSON 替换任意层级的字段
+func redactJSON(v interface{}, fields map[string]bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if fields[strings.ToLower(key)] {
+				value[key] = Redacted
 			} else {
-				w.Write(resDump)
+				value[key] = redactJSON(item, fields)
 			}
-			w.Write(dumpBR)
-			w.Write(dumpLine)
-			return next(resp)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJSON(item, fields)
 		}
 	}
+	return v
 }
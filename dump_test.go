@@ -0,0 +1,131 @@
+package urlx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestDump(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.SetCookie(rw, &http.Cookie{Name: "sid", Value: "secret-sid"})
+		rw.Header().Set(HeaderContentType, "application/json")
+		_, _ = rw.Write([]byte(`{"user":{"name":"a","token":"secret-token"},"items":[1,2,3,4,5,6,7,8,9]}`))
+	}))
+	defer closer()
+
+	config := DumpConfig{
+		RequestBody: true, ResponseBody: true, BodyLimit: 48,
+		RedactHeaders: DefaultRedactHeaders, RedactQuery: []string{"key"}, RedactFields: []string{"password", "token"},
+	}
+	send := func(mw ProcessMw) (string, error) {
+		var body string
+		err := New(context.TODO()).Url(addr + "/login?key=secret-key&x=1").Method(MethodPost).
+			HeaderWith(BearerAuth("secret-bearer")).FormValues(map[string][]string{"user": {"a"}, "password": {"secret-pass"}}).
+			ProcessWith(mw).Process(func(resp *http.Response) error {
+			data, err := io.ReadAll(resp.Body)
+			body = string(data)
+			return err
+		})
+		return body, err
+	}
+
+	var buf bytes.Buffer
+	body, err := send(DumpWith(&buf, config))
+	text := buf.String()
+	eq(t, [][2]any{
+		{err, nil}, {strings.HasSuffix(body, "9]}"), true}, {strings.Contains(text, "secret"), false},
+		{strings.Contains(text, "key=REDACTED&x=1"), true}, {strings.Contains(text, "password=REDACTED&user=a"), true},
+		{strings.Contains(text, `"token":"REDACTED"`), true}, {strings.Contains(text, "...(truncated)"), true},
+	})
+
+	buf.Reset()
+	config.JSONLines, config.BodyLimit = true, -1
+	_, err = send(DumpWith(&buf, config))
+	var record DumpRecord
+	eq(t, [][2]any{{err, nil}, {json.Unmarshal(buf.Bytes(), &record), nil}, {strings.Count(buf.String(), "\n"), 1}})
+	eq(t, [][2]any{
+		{record.Status, http.StatusOK}, {record.RequestHeader.Get(HeaderAuthorization), Redacted}, {record.ResponseHeader.Get("Set-Cookie"), Redacted},
+		{record.ResponseBody, `{"items":[1,2,3,4,5,6,7,8,9],"user":{"name":"a","token":"REDACTED"}}`}, {record.ResponseBodyTruncated, false},
+	})
+
+	dir := filepath.Join(t.TempDir(), "dumps")
+	_, err = send(DumpFiles(dir, config))
+	files, _ := os.ReadDir(dir)
+	eq(t, [][2]any{{err, nil}, {len(files), 1}, {strings.HasSuffix(files[0].Name(), "-0001-POST-127.0.0.1_"+addr[strings.LastIndex(addr, ":")+1:]+".json"), true}})
+
+	body, err = send(Dump(failWriter{}, false, true))
+	eq(t, [][2]any{{strings.Contains(body, "secret-token"), true}, {err != nil && strings.Contains(err.Error(), "disk full"), true}})
+}
+
+func TestDumpStreaming(t *testing.T) {
+	release := make(chan struct{})
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set(HeaderContentType, "text/event-stream")
+		_, _ = rw.Write([]byte("data: 1\n\n"))
+		rw.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-time.After(2 * time.Second):
+		}
+		_, _ = rw.Write([]byte("data: 2\n\n"))
+	}))
+	defer closer()
+
+	// 处理器读到第一个事件后服务器才继续发送, 打印不能预读等待
+	var buf bytes.Buffer
+	start := time.Now()
+	var events []string
+	err := New(context.TODO()).Url(addr).ProcessWith(DumpWith(&buf, DumpConfig{ResponseBody: true})).
+		Process(SSE(func(event Event) error {
+			if events = append(events, event.Data); len(events) == 1 {
+				close(release)
+			}
+			return nil
+		}))
+	eq(t, [][2]any{
+		{err, nil}, {strings.Join(events, ","), "1,2"}, {time.Since(start) < time.Second, true},
+		{strings.Contains(buf.String(), "data: 1\n\ndata: 2\n\n"), true}, {strings.Contains(buf.String(), "(truncated)"), false},
+	})
+}
+
+func TestDumpFilesMax(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.URL.Path))
+	}))
+	defer closer()
+
+	dir := t.TempDir()
+	other := filepath.Join(dir, "keep.txt")
+	_ = os.WriteFile(other, nil, 0o644)
+
+	mw := DumpFiles(dir, DumpConfig{ResponseBody: true, MaxFiles: 2})
+	for _, path := range []string{"/1", "/2", "/3"} {
+		if _, err := New(context.TODO()).Url(addr + path).ProcessWith(mw).Bytes(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 只保留最近的文件, 不删除其他文件
+	files, _ := os.ReadDir(dir)
+	var requests []string
+	for _, f := range files {
+		data, _ := os.ReadFile(filepath.Join(dir, f.Name()))
+		if f.Name() != "keep.txt" {
+			requests = append(requests, strings.Split(string(data), "\n")[1])
+		}
+	}
+	eq(t, [][2]any{{len(files), 3}, {strings.Join(requests, ","), "GET " + addr + "/2,GET " + addr + "/3"}})
+}
@@ -0,0 +1,55 @@
+package urlx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+)
+
+// Result 解码后的值和响应信息
+type Result[T any] struct {
+	Value      T           // 解码后的值
+	StatusCode int         // 状态码
+	Status     string      // 状态
+	Header     http.Header // 响应头
+	URL        *url.URL    // 最终地址, 有跳转时为跳转后的地址
+}
+
+// Fetch 发送请求并使用 decode 解码响应, 返回解码后的值和响应信息
+func Fetch[T any](req *Request, decode func(resp *http.Response, out *T) error) (result Result[T], err error) {
+	err = req.Process(func(resp *http.Response) error {
+		result.StatusCode, result.Status, result.Header = resp.StatusCode, resp.Status, resp.Header
+		if resp.Request != nil {
+			result.URL = resp.Request.URL
+		}
+		return decode(resp, &result.Value)
+	})
+	return
+}
+
+// JSON 发送请求并将响应解码为 JSON
+func JSON[T any](req *Request) (T, error) {
+	result, err := JSONResult[T](req)
+	return result.Value, err
+}
+
+// JSONResult 发送请求并将响应解码为 JSON, 同时返回响应信息
+func JSONResult[T any](req *Request) (Result[T], error) {
+	return Fetch(req, func(resp *http.Response, out *T) error {
+		return json.NewDecoder(resp.Body).Decode(out)
+	})
+}
+
+// XML 发送请求并将响应解码为 XML
+func XML[T any](req *Request) (T, error) {
+	result, err := XMLResult[T](req)
+	return result.Value, err
+}
+
+// XMLResult 发送请求并将响应解码为 XML, 同时返回响应信息
+func XMLResult[T any](req *Request) (Result[T], error) {
+	return Fetch(req, func(resp *http.Response, out *T) error {
+		return xml.NewDecoder(resp.Body).Decode(out)
+	})
+}
@@ -0,0 +1,41 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGeneric(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(rw, r, "/json", http.StatusFound)
+		case "/json":
+			rw.Header().Set("X-Total", "2")
+			_, _ = rw.Write([]byte(`{"items":["a","b"]}`))
+		case "/xml":
+			_, _ = rw.Write([]byte(`<item id="1"><name>x</name></item>`))
+		}
+	}))
+	defer closer()
+
+	type list struct {
+		Items []string `json:"items"`
+	}
+	result, err := JSONResult[list](New(context.TODO()).Url(addr + "/old"))
+	eq(t, [][2]any{{err, nil}, {len(result.Value.Items), 2}, {result.StatusCode, http.StatusOK}, {result.Header.Get("X-Total"), "2"}, {result.URL.Path, "/json"}})
+
+	m, err := JSON[map[string][]string](New(context.TODO()).Url(addr + "/json"))
+	eq(t, [][2]any{{err, nil}, {m["items"][1], "b"}})
+
+	type item struct {
+		ID   int    `xml:"id,attr"`
+		Name string `xml:"name"`
+	}
+	x, err := XML[item](New(context.TODO()).Url(addr + "/xml"))
+	eq(t, [][2]any{{err, nil}, {x.ID, 1}, {x.Name, "x"}})
+
+	_, err = JSON[list](New(context.TODO()).Url(addr + "/xml"))
+	eq(t, [][2]any{{err != nil, true}})
+}
@@ -0,0 +1,438 @@
+package urlx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultHARBodyLimit HAR 中每个请求和响应内容最多记录的字节数
+const DefaultHARBodyLimit = 1 << 20
+
+// ErrHARPostData 记录的请求内容不完整或者编码不支持, 无法重放
+var ErrHARPostData = errors.New("har: post data cannot be replayed")
+
+// HAR HTTP Archive 1.2, http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog HAR 日志
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+	Comment string     `json:"comment,omitempty"`
+}
+
+// HARCreator 创建 HAR 的程序
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry 一次往返
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // 总耗时, 毫秒
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"` // 请求失败时为错误信息
+}
+
+// HARRequest 请求
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse 响应
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue 请求头, 响应头和 Query 参数
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie Cookie
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// HARPostData 请求内容, 二进制内容的 Encoding 为 base64, 内容被截断或读取失败时 Truncated 为 true
+type HARPostData struct {
+	MimeType  string `json:"mimeType"`
+	Text      string `json:"text"`
+	Encoding  string `json:"_encoding,omitempty"`
+	Truncated bool   `json:"_truncated,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// HARContent 响应内容, 二进制内容的 Encoding 为 base64
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings 耗时, 毫秒, 不适用的为 -1
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // 包括 SSL
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// LoadHAR 读取 HAR 文件
+func LoadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har HAR
+	if err = json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+	return &har, nil
+}
+
+// Save 写入文件
+func (h *HAR) Save(path string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Requests 将所有的记录转换为请求
+func (h *HAR) Requests(ctx context.Context) []*Request {
+	requests := make([]*Request, len(h.Log.Entries))
+	for i := range h.Log.Entries {
+		requests[i] = h.Log.Entries[i].Request.Request(ctx)
+	}
+	return requests
+}
+
+// harSkipHeaders 重放时忽略的请求头, 由客户端自动设置
+var harSkipHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"accept-encoding":   true,
+	"connection":        true,
+	"transfer-encoding": true,
+}
+
+// Request 转换为请求, 用于重放浏览器抓取的请求. 忽略 HTTP/2 伪首部和由客户端设置的请求头,
+// 请求内容不完整或者编码不支持时, 发送请求返回 ErrHARPostData
+func (r *HARRequest) Request(ctx context.Context) *Request {
+	headers := make([]HARNameValue, 0, len(r.Headers))
+	for _, header := range r.Headers {
+		if !strings.HasPrefix(header.Name, ":") && !harSkipHeaders[strings.ToLower(header.Name)] {
+			headers = append(headers, header)
+		}
+	}
+
+	req := New(ctx).Method(r.Method).Url(r.URL).HeaderWith(func(h http.Header) {
+		for _, header := range headers {
+			h.Add(header.Name, header.Value)
+		}
+	})
+	if r.PostData != nil {
+		postData := *r.PostData
+		req.Body(func() (contentType string, body io.Reader, err error) {
+			data, err := postData.bytes()
+			if err != nil {
+				return "", nil, err
+			}
+			return postData.MimeType, bytes.NewReader(data), nil
+		})
+	}
+	return req
+}
+
+// bytes 解码请求内容
+func (p *HARPostData) bytes() ([]byte, error) {
+	if p.Truncated {
+		return nil, fmt.Errorf("%w: truncated", ErrHARPostData)
+	}
+	switch p.Encoding {
+	case "":
+		return []byte(p.Text), nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(p.Text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrHARPostData, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported encoding %q", ErrHARPostData, p.Encoding)
+}
+
+// HARRecorder 记录每次往返(包括重试和跳转)的请求和响应, 响应内容在读完或关闭后记录
+type HARRecorder struct {
+	bodyLimit int64
+
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// NewHARRecorder 创建 HAR 记录器, bodyLimit 每个请求和响应内容最多记录的字节数, 0使用默认值, 小于0不记录内容
+func NewHARRecorder(bodyLimit int64) *HARRecorder {
+	if bodyLimit == 0 {
+		bodyLimit = DefaultHARBodyLimit
+	}
+	return &HARRecorder{bodyLimit: bodyLimit}
+}
+
+// RecordHAR 记录到 HAR
+func RecordHAR(r *HARRecorder) Option {
+	return Transport(r.Mw)
+}
+
+// HAR 已记录的 HAR, 按开始时间排序
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	entries := append([]HAREntry(nil), r.entries...)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedDateTime.Before(entries[j].StartedDateTime) })
+	return &HAR{Log: HARLog{Version: "1.2", Creator: HARCreator{Name: "urlx", Version: "1"}, Entries: entries}}
+}
+
+// Save 写入文件
+func (r *HARRecorder) Save(path string) error {
+	return r.HAR().Save(path)
+}
+
+// Mw 请求中间件
+func (r *HARRecorder) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		entry := HAREntry{Request: r.request(req)}
+
+		resp, trace, err := traceRoundTrip(next, req, nil)
+		if err != nil {
+			entry.Comment = err.Error()
+			entry.Response = HARResponse{Cookies: []HARCookie{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1}
+			r.add(entry, trace.snapshot())
+			return nil, err
+		}
+
+		entry.Response = HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+			HTTPVersion: resp.Proto,
+			Cookies:     harCookies(resp.Cookies()),
+			Headers:     harHeaders(resp.Header),
+			Content:     HARContent{MimeType: resp.Header.Get(HeaderContentType)},
+			RedirectURL: resp.Header.Get(HeaderLocation),
+			HeadersSize: -1,
+			BodySize:    resp.ContentLength,
+		}
+		resp.Body = &harBody{ReadCloser: resp.Body, limit: r.bodyLimit, done: func(body *harBody) {
+			content := &entry.Response.Content
+			content.Size = body.size
+			content.Text, content.Encoding = harText(body.buf.Bytes())
+			if body.size > int64(body.buf.Len()) {
+				content.Comment = "truncated"
+			}
+			if entry.Response.BodySize < 0 {
+				entry.Response.BodySize = body.size
+			}
+			r.add(entry, trace.snapshot())
+		}}
+		return resp, nil
+	})
+}
+
+// add 保存记录
+func (r *HARRecorder) add(entry HAREntry, timing Timing) {
+	entry.StartedDateTime = timing.Start
+	entry.Time = harMillis(timing.Total)
+	if host, _, err := net.SplitHostPort(timing.RemoteAddr); err == nil {
+		entry.ServerIPAddress = host
+	}
+
+	wait := timing.TTFB - timing.DNS - timing.Connect
+	if wait < 0 {
+		wait = 0
+	}
+	entry.Timings = HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: harMillis(wait), Receive: harMillis(timing.Transfer)}
+	if timing.DNS > 0 {
+		entry.Timings.DNS = harMillis(timing.DNS)
+	}
+	if timing.Connect > 0 || timing.TLS > 0 {
+		entry.Timings.Connect = harMillis(timing.Connect + timing.TLS)
+	}
+	if timing.TLS > 0 {
+		entry.Timings.SSL = harMillis(timing.TLS)
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+// request 记录请求
+func (r *HARRecorder) request(req *http.Request) HARRequest {
+	hr := HARRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     harHeaders(req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if hr.HTTPVersion == "" {
+		hr.HTTPVersion = "HTTP/1.1"
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			hr.QueryString = append(hr.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(hr.QueryString, func(i, j int) bool { return hr.QueryString[i].Name < hr.QueryString[j].Name })
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return hr
+	}
+	hr.BodySize = req.ContentLength
+	hr.PostData = &HARPostData{MimeType: req.Header.Get(HeaderContentType)}
+	if data, err := readRequestBody(req); err != nil {
+		hr.PostData.Truncated, hr.PostData.Comment = true, err.Error()
+	} else {
+		limit := r.bodyLimit
+		if limit < 0 {
+			limit = 0
+		}
+		if int64(len(data)) > limit {
+			data, hr.PostData.Truncated, hr.PostData.Comment = data[:limit], true, "truncated"
+		}
+		hr.PostData.Text, hr.PostData.Encoding = harText(data)
+	}
+	return hr
+}
+
+// harBody 读取时记录响应内容, 读完或关闭时保存记录
+type harBody struct {
+	io.ReadCloser
+	limit int64
+	buf   bytes.Buffer
+	size  int64
+	once  sync.Once
+	done  func(body *harBody)
+}
+
+func (b *harBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if n > 0 {
+		b.size += int64(n)
+		if remain := b.limit - int64(b.buf.Len()); remain > 0 {
+			if int64(n) < remain {
+				remain = int64(n)
+			}
+			b.buf.Write(p[:remain])
+		}
+	}
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b) })
+	}
+	return
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b) })
+	return err
+}
+
+// harText 内容为文本直接返回, 否则使用 base64 编码
+func harText(data []byte) (text, encoding string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64"
+}
+
+// harHeaders 转换请求头, 按名称排序
+func harHeaders(header http.Header) []HARNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// harCookies 转换 Cookie
+func harCookies(cookies []*http.Cookie) []HARCookie {
+	result := make([]HARCookie, 0, len(cookies))
+	for _, c := range cookies {
+		hc := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			expires := c.Expires
+			hc.Expires = &expires
+		}
+		result = append(result, hc)
+	}
+	return result
+}
+
+// harMillis 毫秒
+func harMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
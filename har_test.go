@@ -0,0 +1,85 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHAR(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(rw, &http.Cookie{Name: "sid", Value: "s1", HttpOnly: true})
+			http.Redirect(rw, r, "/home", http.StatusSeeOther)
+			return
+		}
+		sid, _ := r.Cookie("sid")
+		body, _ := io.ReadAll(r.Body)
+		rw.Header().Set(HeaderContentType, "text/plain")
+		_, _ = rw.Write([]byte(r.Method + " " + sid.Value + " " + r.Header.Get("X-Token") + string(body) + strings.Repeat(".", 20)))
+	}))
+	defer closer()
+
+	recorder := NewHARRecorder(10)
+	data, err := New(context.TODO(), RecordHAR(recorder), CookieEnabled()).Url(addr + "/login?user=a").Method(MethodPost).
+		HeaderWith(HeaderSet("X-Token", "x")).FormValues(map[string][]string{"p": {"1"}}).Bytes()
+	eq(t, [][2]any{{err, nil}, {string(data), "GET s1 x" + strings.Repeat(".", 20)}})
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err = recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	har, err := LoadHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := har.Log.Entries
+	eq(t, [][2]any{{har.Log.Version, "1.2"}, {len(entries), 2}})
+	login, home := entries[0], entries[1]
+	eq(t, [][2]any{
+		{login.Request.Method, MethodPost}, {login.Request.QueryString[0].Value, "a"}, {login.Request.PostData.Text, "p=1"},
+		{login.Response.Status, http.StatusSeeOther}, {login.Response.StatusText, "See Other"}, {login.Response.RedirectURL, "/home"},
+		{login.Response.Cookies[0].Name, "sid"}, {login.Response.Cookies[0].HTTPOnly, true}, {login.ServerIPAddress, "127.0.0.1"},
+		{home.Request.Cookies[0].Value, "s1"}, {home.Response.Content.Size, int64(28)}, {home.Response.Content.Text, "GET s1 x.."},
+		{home.Response.Content.Comment, "truncated"}, {home.Timings.Connect, float64(-1)}, {home.Time > 0, true},
+	})
+
+	// 重放第一条记录
+	data, err = har.Requests(context.TODO())[0].With(CookieEnabled()).Bytes()
+	eq(t, [][2]any{{err, nil}, {string(data), "GET s1 x" + strings.Repeat(".", 20)}})
+}
+
+func TestHARPostData(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		_, _ = rw.Write(body)
+	}))
+	defer closer()
+
+	recorder := NewHARRecorder(4)
+	for _, body := range []string{"\xff\x00\xfe", "中文内容"} {
+		if err := New(context.TODO(), RecordHAR(recorder)).Url(addr).Method(MethodPost).Body(func() (string, io.Reader, error) {
+			return "application/octet-stream", strings.NewReader(body), nil
+		}).Process(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := recorder.HAR().Log.Entries
+	binary, truncated := entries[0].Request.PostData, entries[1].Request.PostData
+	eq(t, [][2]any{{binary.Encoding, "base64"}, {binary.Truncated, false}, {truncated.Truncated, true}})
+
+	// 二进制内容解码后重放, 截断的内容不重放
+	data, err := entries[0].Request.Request(context.TODO()).Bytes()
+	eq(t, [][2]any{{err, nil}, {string(data), "\xff\x00\xfe"}})
+	atomic.StoreInt32(&hits, 0)
+	_, err = entries[1].Request.Request(context.TODO()).Bytes()
+	eq(t, [][2]any{{errors.Is(err, ErrHARPostData), true}, {atomic.LoadInt32(&hits), int32(0)}})
+}
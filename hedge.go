@@ -0,0 +1,119 @@
+package urlx
+
+import (
+	"net/http"
+	"time"
+)
+
+// Hedge 对冲请求: 发出请求后 delay 时间内未收到响应头, 则再发出一个相同的请求, 最多同时 max 个,
+// 使用最先成功(非 5xx)的响应, 取消其余的请求. 只对没有请求内容的 GET, HEAD, OPTIONS 请求生效
+func (c *Request) Hedge(delay time.Duration, max int) *Request {
+	c.hedgeDelay, c.hedgeMax = delay, max
+	return c
+}
+
+// Hedge 对冲请求
+func Hedge(delay time.Duration, max int) Option {
+	return func(r *Request) error {
+		r.Hedge(delay, max)
+		return nil
+	}
+}
+
+// do 发送一次请求, 设置了对冲则并发发送
+func (c *Request) do(req *http.Request) (*http.Response, *attemptTimer, error) {
+	if c.hedgeMax < 2 || !hedgeable(req) {
+		ctx, timer := c.attemptContext()
+		resp, err := timer.watch(c.client.Do(req.WithContext(ctx)))
+		return resp, timer, err
+	}
+	return c.hedge(req)
+}
+
+// hedgeResult 对冲请求的结果
+type hedgeResult struct {
+	resp  *http.Response
+	err   error
+	timer *attemptTimer
+}
+
+// ok 是否成功
+func (r hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode < 500
+}
+
+// hedge 并发发送请求, 返回最先成功的响应, 都失败则返回最后一个结果
+func (c *Request) hedge(req *http.Request) (*http.Response, *attemptTimer, error) {
+	var (
+		results = make(chan hedgeResult, c.hedgeMax)
+		timers  []*attemptTimer
+		running int
+		last    *hedgeResult
+	)
+
+	launch := func() {
+		ctx, timer := c.attemptContext()
+		timers = append(timers, timer)
+		running++
+		go func() {
+			resp, err := timer.watch(c.client.Do(req.Clone(ctx)))
+			results <- hedgeResult{resp: resp, err: err, timer: timer}
+		}()
+	}
+
+	next := time.NewTimer(c.hedgeDelay)
+	defer next.Stop()
+
+	launch()
+	for running > 0 {
+		select {
+		case r := <-results:
+			running--
+			if r.ok() {
+				// 取消其余的请求, 并在后台读取丢弃它们的响应
+				for _, timer := range timers {
+					if timer != r.timer {
+						timer.stop()
+						timer.cancel()
+					}
+				}
+				if last != nil && last.resp != nil {
+					closes(last.resp.Body)
+				}
+				go discardHedges(results, running)
+				return r.resp, r.timer, nil
+			}
+			if last != nil && last.resp != nil {
+				closes(last.resp.Body)
+			}
+			last = &r
+			if len(timers) < c.hedgeMax && c.ctx.Err() == nil {
+				launch()
+			}
+		case <-next.C:
+			if len(timers) < c.hedgeMax {
+				launch()
+				next.Reset(c.hedgeDelay)
+			}
+		}
+	}
+	return last.resp, last.timer, last.err
+}
+
+// discardHedges 丢弃未使用的响应
+func discardHedges(results <-chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.resp != nil {
+			drain(r.resp.Body)
+		}
+	}
+}
+
+// hedgeable 是否可以对冲
+func hedgeable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
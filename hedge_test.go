@@ -0,0 +1,43 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedge(t *testing.T) {
+	var hits int32
+	canceled := make(chan struct{})
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+			_, _ = rw.Write([]byte("slow"))
+			return
+		}
+		_, _ = rw.Write([]byte("fast"))
+	}))
+	defer closer()
+
+	start := time.Now()
+	data, err := New(context.TODO()).Url(addr).Hedge(50*time.Millisecond, 3).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "fast"}, {atomic.LoadInt32(&hits), int32(2)}})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("hedge too slow: %s", elapsed)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow attempt not canceled")
+	}
+}
@@ -0,0 +1,143 @@
+package urlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// HTTPErrorBodyLimit HTTPError 中保留的响应内容最大字节数
+var HTTPErrorBodyLimit int64 = 4 << 10
+
+// HTTPError 非成功状态码的响应错误
+type HTTPError struct {
+	Method     string      // 请求方法
+	URL        string      // 请求地址(隐藏密码)
+	StatusCode int         // 状态码
+	Status     string      // 状态
+	Header     http.Header // 响应头
+	Body       []byte      // 响应内容片段, 最多 HTTPErrorBodyLimit 字节
+	Payload    any         // 解码后的错误内容, 问题详情为 *Problem, 其他JSON为 any
+}
+
+// Problem 问题详情 RFC 7807
+type Problem struct {
+	XMLName    xml.Name       `json:"-" xml:"problem"`
+	Type       string         `json:"type,omitempty" xml:"type,omitempty"`
+	Title      string         `json:"title,omitempty" xml:"title,omitempty"`
+	Status     int            `json:"status,omitempty" xml:"status,omitempty"`
+	Detail     string         `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty" xml:"instance,omitempty"`
+	Extensions map[string]any `json:"-" xml:"-"` // 扩展字段(仅JSON)
+}
+
+// Error 错误信息
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if p, ok := e.Payload.(*Problem); ok {
+		if p.Title != "" {
+			msg += ": " + p.Title
+		}
+		if p.Detail != "" {
+			msg += ": " + p.Detail
+		}
+	}
+	return msg
+}
+
+// Decode 按响应的 Content-Type 将错误内容解码为 JSON 或者 XML
+func (e *HTTPError) Decode(out any) error {
+	mediaType, _, _ := mime.ParseMediaType(e.Header.Get(HeaderContentType))
+	if isXMLType(mediaType) {
+		return xml.Unmarshal(e.Body, out)
+	}
+	return json.Unmarshal(e.Body, out)
+}
+
+// StatusCheck 将 ok 返回 false 的状态码转换为 *HTTPError, ok 为空则 2xx 视为成功
+func StatusCheck(ok func(status int) bool) ProcessMw {
+	if ok == nil {
+		ok = func(status int) bool { return status >= 200 && status < 300 }
+	}
+	return func(next Process) Process {
+		return func(resp *http.Response) error {
+			if ok(resp.StatusCode) {
+				return next(resp)
+			}
+			return newHTTPError(resp)
+		}
+	}
+}
+
+// CheckStatus 2xx 以外的状态码返回 *HTTPError
+var CheckStatus = StatusCheck(nil)
+
+// StatusOf 获取错误中的响应状态码, 不是 *HTTPError 则返回0
+func StatusOf(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode
+	}
+	return 0
+}
+
+// newHTTPError 读取响应构造错误
+func newHTTPError(resp *http.Response) *HTTPError {
+	e := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header}
+	if req := resp.Request; req != nil {
+		e.Method = req.Method
+		if req.URL != nil {
+			e.URL = req.URL.Redacted()
+		}
+	}
+	if resp.Body != nil {
+		e.Body, _ = io.ReadAll(io.LimitReader(resp.Body, HTTPErrorBodyLimit))
+	}
+	e.Payload = decodePayload(resp.Header.Get(HeaderContentType), e.Body)
+	return e
+}
+
+// decodePayload 解码问题详情或者JSON, 无法解码返回 nil
+func decodePayload(contentType string, body []byte) any {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/problem+json":
+		var p Problem
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil
+		}
+		if err := json.Unmarshal(body, &p.Extensions); err == nil {
+			for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+				delete(p.Extensions, key)
+			}
+		}
+		return &p
+	case mediaType == "application/problem+xml":
+		var p Problem
+		if err := xml.Unmarshal(body, &p); err != nil {
+			return nil
+		}
+		return &p
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil
+		}
+		return v
+	}
+	return nil
+}
+
+// isXMLType 是否XML格式
+func isXMLType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
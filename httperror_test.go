@@ -0,0 +1,38 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set(HeaderContentType, "application/problem+json")
+		rw.WriteHeader(http.StatusForbidden)
+		_, _ = rw.Write([]byte(`{"title":"denied","detail":"no access","code":7}`))
+	}))
+	defer closer()
+
+	err := Default(context.TODO()).Url(addr + "/res").Process(nil)
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("want *HTTPError, got %v", err)
+	}
+	p, _ := he.Payload.(*Problem)
+	if p == nil {
+		t.Fatalf("want *Problem payload, got %T", he.Payload)
+	}
+	eq(t, [][2]any{
+		{he.Method, MethodGet},
+		{he.URL, addr + "/res"},
+		{StatusOf(err), http.StatusForbidden},
+		{p.Detail, "no access"},
+		{p.Extensions["code"], float64(7)},
+	})
+
+	if err = New(context.TODO()).Url(addr).Process(nil); err != nil {
+		t.Fatalf("status check should be opt-in: %v", err)
+	}
+}
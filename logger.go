@@ -0,0 +1,88 @@
+package urlx
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// 日志事件类型
+const (
+	LogAttempt = "attempt" // 完成一次请求
+	LogRetry   = "retry"   // 准备重试
+	LogGiveUp  = "giveup"  // 不再重试, 返回错误
+	LogWarn    = "warn"    // 警告, 如丢弃了 Cookie 中无效的字节
+)
+
+// LogEvent 日志事件
+type LogEvent struct {
+	Kind    string        // 事件类型
+	Attempt int           // 第几次请求, 从1开始
+	Method  string        // 请求方法
+	URL     string        // 请求地址
+	Status  int           // 响应状态码, 出错时为0
+	Latency time.Duration // 本次请求耗时(到收到响应头)
+	Err     error         // 错误
+	Backoff time.Duration // 重试前的等待时间
+}
+
+// Logger 日志记录
+type Logger interface {
+	Log(ctx context.Context, event LogEvent)
+}
+
+// LoggerFunc 函数形式的 Logger
+type LoggerFunc func(ctx context.Context, event LogEvent)
+
+// Log 实现 Logger
+func (f LoggerFunc) Log(ctx context.Context, event LogEvent) { f(ctx, event) }
+
+// NopLogger 不记录日志, 默认的日志记录
+var NopLogger Logger = LoggerFunc(func(context.Context, LogEvent) {})
+
+// StdLogger 使用标准库 log.Logger 记录重试, 出错和警告, l 为空则使用 log.Default()
+func StdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return LoggerFunc(func(ctx context.Context, e LogEvent) {
+		switch e.Kind {
+		case LogRetry:
+			if e.Err != nil {
+				l.Printf("%s %s 第%d次出错: %v, %s后重试", e.Method, e.URL, e.Attempt, e.Err, e.Backoff)
+			} else {
+				l.Printf("%s %s 第%d次响应: %d, %s后重试", e.Method, e.URL, e.Attempt, e.Status, e.Backoff)
+			}
+		case LogGiveUp:
+			l.Printf("%s %s 第%d次出错: %v, 返回错误", e.Method, e.URL, e.Attempt, e.Err)
+		case LogWarn:
+			l.Printf("%s %s 警告: %v", e.Method, e.URL, e.Err)
+		}
+	})
+}
+
+// UseLogger 设置日志记录
+func (c *Request) UseLogger(logger Logger) *Request {
+	c.logger = logger
+	return c
+}
+
+// UseLogger 设置日志记录
+func UseLogger(logger Logger) Option {
+	return func(r *Request) error {
+		r.UseLogger(logger)
+		return nil
+	}
+}
+
+// UseLogger 设置默认的日志记录
+func (s *Session) UseLogger(logger Logger) *Session {
+	return s.update(func(tpl *Request) { tpl.UseLogger(logger) })
+}
+
+// log 记录日志
+func (c *Request) log(event LogEvent) {
+	if c.logger != nil {
+		c.logger.Log(c.ctx, event)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build go1.21
+// +build go1.21
+
+package urlx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger 使用 log/slog 记录结构化日志, 完成请求为 Debug, 重试和警告为 Warn, 放弃为 Error
+func SlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return LoggerFunc(func(ctx context.Context, e LogEvent) {
+		level := slog.LevelDebug
+		switch e.Kind {
+		case LogRetry, LogWarn:
+			level = slog.LevelWarn
+		case LogGiveUp:
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.Int("attempt", e.Attempt),
+			slog.String("method", e.Method),
+			slog.String("url", e.URL),
+			slog.Duration("latency", e.Latency),
+		}
+		if e.Status != 0 {
+			attrs = append(attrs, slog.Int("status", e.Status))
+		}
+		if e.Err != nil {
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		if e.Kind == LogRetry {
+			attrs = append(attrs, slog.Duration("backoff", e.Backoff))
+		}
+		l.LogAttrs(ctx, level, "urlx "+e.Kind, attrs...)
+	})
+}
@@ -0,0 +1,66 @@
+//go:build go1.21
+// +build go1.21
+
+package urlx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer closer()
+
+	var buf bytes.Buffer
+	logger := SlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if err := New(context.TODO()).Url(addr).TryAt(time.Millisecond).UseLogger(logger).Process(nil); err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(context.TODO(), LogEvent{Kind: LogGiveUp, Attempt: 3, Method: MethodGet, URL: addr, Err: errors.New("reset")})
+	logger.Log(context.TODO(), LogEvent{Kind: LogWarn, Method: MethodGet, URL: addr, Err: errors.New("invalid byte")})
+
+	type record struct {
+		Level   string
+		Msg     string
+		Attempt int
+		Method  string
+		URL     string
+		Status  int
+		Backoff *time.Duration
+		Error   string
+	}
+	var records []record
+	for dec := json.NewDecoder(&buf); dec.More(); {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+
+	ms := time.Millisecond
+	eq(t, [][2]any{
+		{len(records), 5},
+		{records[0], record{Level: "DEBUG", Msg: "urlx attempt", Attempt: 1, Method: MethodGet, URL: addr, Status: 503}},
+		{records[1].Level, "WARN"},
+		{records[1].Msg, "urlx retry"},
+		{records[1].Status, 503},
+		{*records[1].Backoff, ms},
+		{records[2], record{Level: "DEBUG", Msg: "urlx attempt", Attempt: 2, Method: MethodGet, URL: addr, Status: 200}},
+		{records[3], record{Level: "ERROR", Msg: "urlx giveup", Attempt: 3, Method: MethodGet, URL: addr, Error: "reset"}},
+		{records[4], record{Level: "WARN", Msg: "urlx warn", Method: MethodGet, URL: addr, Error: "invalid byte"}},
+	})
+}
@@ -0,0 +1,29 @@
+package urlx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := StdLogger(log.New(&buf, "", 0))
+	for _, e := range []LogEvent{
+		{Kind: LogAttempt, Attempt: 1, Method: MethodGet, URL: "http://a", Status: 503},
+		{Kind: LogRetry, Attempt: 1, Method: MethodGet, URL: "http://a", Status: 503, Backoff: time.Second},
+		{Kind: LogRetry, Attempt: 2, Method: MethodGet, URL: "http://a", Err: errors.New("reset"), Backoff: 2 * time.Second},
+		{Kind: LogGiveUp, Attempt: 3, Method: MethodGet, URL: "http://a", Err: errors.New("reset")},
+		{Kind: LogWarn, Method: MethodGet, URL: "http://a", Err: errors.New("invalid byte")},
+	} {
+		logger.Log(context.TODO(), e)
+	}
+	// 完成请求不记录
+	eq(t, [][2]any{{buf.String(), "GET http://a 第1次响应: 503, 1s后重试\n" +
+		"GET http://a 第2次出错: reset, 2s后重试\n" +
+		"GET http://a 第3次出错: reset, 返回错误\n" +
+		"GET http://a 警告: invalid byte\n"}})
+}
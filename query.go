@@ -0,0 +1,314 @@
+package urlx
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// QueryOption 请求Query参数处理
+type QueryOption = func(query url.Values) error
+
+// QueryWith 处理请求Query参数, 会与链接中已有的参数合并
+func (c *Request) QueryWith(options ...QueryOption) *Request {
+	c.queries = append(c.queries, options...)
+	return c
+}
+
+// QueryValues 添加请求Query参数
+func (c *Request) QueryValues(values url.Values) *Request {
+	return c.QueryWith(func(query url.Values) error {
+		for key, vs := range values {
+			query[key] = append(query[key], vs...)
+		}
+		return nil
+	})
+}
+
+// QuerySet 设置请求Query参数, 替换已有的值
+func (c *Request) QuerySet(key string, values ...string) *Request {
+	return c.QueryWith(func(query url.Values) error {
+		query[key] = append([]string(nil), values...)
+		return nil
+	})
+}
+
+// QueryAdd 添加请求Query参数
+func (c *Request) QueryAdd(key string, values ...string) *Request {
+	return c.QueryWith(func(query url.Values) error {
+		query[key] = append(query[key], values...)
+		return nil
+	})
+}
+
+// QueryDel 删除请求Query参数
+func (c *Request) QueryDel(keys ...string) *Request {
+	return c.QueryWith(func(query url.Values) error {
+		for _, key := range keys {
+			query.Del(key)
+		}
+		return nil
+	})
+}
+
+// QueryStruct 将结构体编码为请求Query参数, 参见 QueryEncode
+func (c *Request) QueryStruct(v any) *Request {
+	return c.QueryWith(func(query url.Values) error {
+		values, err := QueryEncode(v)
+		if err != nil {
+			return err
+		}
+		for key, vs := range values {
+			query[key] = append(query[key], vs...)
+		}
+		return nil
+	})
+}
+
+// PathParam 设置链接中的路径参数, 替换链接路径中的 {name}
+func (c *Request) PathParam(name, value string) *Request {
+	if c.params == nil {
+		c.params = map[string]string{}
+	}
+	c.params[name] = value
+	return c
+}
+
+// PathParams 设置链接中的路径参数
+func (c *Request) PathParams(params map[string]string) *Request {
+	for name, value := range params {
+		c.PathParam(name, value)
+	}
+	return c
+}
+
+// buildURL 替换路径参数, 拼接原始Query参数, 解析基础地址并合并Query参数
+func (c *Request) buildURL() (string, error) {
+	rawURL := c.url
+	if len(c.params) > 0 {
+		path, rawQuery := rawURL, ""
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			path, rawQuery = rawURL[:i], rawURL[i:]
+		}
+		for name, value := range c.params {
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+		}
+		rawURL = path + rawQuery
+	}
+
+	if c.query != "" {
+		fragment := ""
+		if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+			rawURL, fragment = rawURL[:i], rawURL[i:]
+		}
+		if strings.Contains(rawURL, "?") {
+			rawURL += "&" + c.query
+		} else {
+			rawURL += "?" + c.query
+		}
+		rawURL += fragment
+	}
+
+	if c.base == "" && len(c.queries) == 0 {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if c.base != "" {
+		base, err := url.Parse(c.base)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+			if base.RawPath != "" {
+				base.RawPath += "/"
+			}
+		}
+		u = base.ResolveReference(u)
+	}
+
+	if len(c.queries) > 0 {
+		if u.RawQuery, err = mergeQuery(u.RawQuery, c.queries); err != nil {
+			return "", err
+		}
+	}
+	return u.String(), nil
+}
+
+// mergeQuery 将Query参数处理应用到原始参数上, 未改变的参数保持原来的顺序和编码, 改变或新增的参数编码后追加到最后
+func mergeQuery(rawQuery string, options []QueryOption) (string, error) {
+	var (
+		pairs  []string
+		keys   []string
+		before = url.Values{}
+	)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		pairs, keys = append(pairs, pair), append(keys, key)
+		before[key] = append(before[key], value)
+	}
+
+	after := make(url.Values, len(before))
+	for key, values := range before {
+		after[key] = append([]string(nil), values...)
+	}
+	for _, apply := range options {
+		if err := apply(after); err != nil {
+			return "", err
+		}
+	}
+
+	var out []string
+	for i, pair := range pairs {
+		if equalStrings(before[keys[i]], after[keys[i]]) {
+			out = append(out, pair)
+		}
+	}
+	changed := url.Values{}
+	for key, values := range after {
+		if !equalStrings(before[key], values) {
+			changed[key] = values
+		}
+	}
+	if encoded := changed.Encode(); encoded != "" {
+		out = append(out, encoded)
+	}
+	return strings.Join(out, "&"), nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// QueryEncode 将结构体编码为 url.Values, 支持 go-querystring 的基本标签 `url:"name,omitempty"` 和 `url:"-"`,
+// 匿名结构体的字段展开, 嵌套结构体编码为 name[field], 切片编码为多个同名参数, 时间使用 RFC3339.
+// 其他标签选项(comma, brackets, unix 等)返回错误, 需要时使用 QueryWith(form.Query(v))
+func QueryEncode(v any) (url.Values, error) {
+	values := url.Values{}
+	switch o := v.(type) {
+	case url.Values:
+		for key, vs := range o {
+			values[key] = append(values[key], vs...)
+		}
+		return values, nil
+	case map[string]string:
+		for key, s := range o {
+			values.Set(key, s)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return values, nil
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query: expects struct input, got %v", rv.Kind())
+	}
+	if err := encodeQueryStruct(values, rv, ""); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// encodeQueryStruct 编码结构体字段, scope 为嵌套结构体的前缀
+func encodeQueryStruct(values url.Values, rv reflect.Value, scope string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field, fv := rt.Field(i), rv.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("url"), ",")
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		var omitEmpty bool
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "omitempty":
+				omitEmpty = true
+			default:
+				return fmt.Errorf("query: field %s: unsupported url tag option %q, use form.Query", field.Name, opt)
+			}
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		isStruct := fv.Kind() == reflect.Struct && fv.Type() != timeType
+		if name == "" && field.Anonymous && isStruct {
+			if err := encodeQueryStruct(values, fv, scope); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if scope != "" {
+			name = scope + "[" + name + "]"
+		}
+
+		switch {
+		case isStruct:
+			if err := encodeQueryStruct(values, fv, name); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Array, fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, queryString(fv.Index(j)))
+			}
+		default:
+			values.Add(name, queryString(fv))
+		}
+	}
+	return nil
+}
+
+// queryString 基础类型转换为字符串, 空指针为空字符串
+func queryString(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	case v.Kind() == reflect.Slice:
+		return string(v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,92 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryEncode(t *testing.T) {
+	type Page struct {
+		Page int `url:"page"`
+		Size int `url:"size,omitempty"`
+	}
+	type Filter struct {
+		Status string `url:"status"`
+	}
+	type Params struct {
+		Page
+		Name   string    `url:"name"`
+		IDs    []int     `url:"id"`
+		Filter Filter    `url:"filter"`
+		Since  time.Time `url:"since"`
+		Skip   string    `url:"-"`
+		Empty  *string   `url:"empty,omitempty"`
+		Nil    *string
+	}
+
+	values, err := QueryEncode(&Params{
+		Page:   Page{Page: 2},
+		Name:   "a b",
+		IDs:    []int{1, 2},
+		Filter: Filter{Status: "open"},
+		Since:  time.Unix(1600000000, 0).UTC(),
+		Skip:   "skip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{values.Encode(), "Nil=&filter%5Bstatus%5D=open&id=1&id=2&name=a+b&page=2&since=2020-09-13T12%3A26%3A40Z"}})
+
+	// 不支持的标签选项返回错误, 而不是按不同的方式编码
+	type Unsupported struct {
+		Filter struct {
+			Tags []string `url:"tags,comma"`
+		} `url:"filter"`
+	}
+	_, err = QueryEncode(Unsupported{})
+	eq(t, [][2]any{{err != nil && strings.Contains(err.Error(), `"comma"`), true}})
+}
+
+func TestQueryRaw(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.URL.RawQuery))
+	}))
+	defer closer()
+
+	data, err := New(context.TODO()).Url(addr + "/?z=1").Query("a=1;b=2").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "z=1&a=1;b=2"}})
+
+	// 预签名链接中未改变的参数保持原来的顺序和编码
+	data, err = New(context.TODO()).Url(addr+"/?X-Sig=a%2Fb&B=2&A=1").QuerySet("A", "3").QueryAdd("c", "x y").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "X-Sig=a%2Fb&B=2&A=3&c=x+y"}})
+}
+
+func TestQueryAndPath(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer closer()
+
+	data, err := New(context.TODO()).Url(addr+"/users/{id}/files/{name}?keep=1&drop=2").
+		PathParam("id", "42").
+		PathParams(map[string]string{"name": "a/b c"}).
+		QueryValues(url.Values{"q": {"中文"}}).
+		QuerySet("keep", "one").
+		QueryDel("drop").
+		QueryAdd("q", "&").
+		Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "/users/42/files/a%2Fb%20c?keep=one&q=%E4%B8%AD%E6%96%87&q=%26"}})
+}
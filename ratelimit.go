@@ -0,0 +1,197 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter 令牌桶限流器, 默认按主机分别限流, 可以在多个请求和会话之间共享.
+// 收到 429 时该主机的速率减半并按 Retry-After 暂停, 之后每次成功响应逐步恢复
+type RateLimiter struct {
+	rate    float64                        // 每秒请求数
+	burst   int                            // 桶容量
+	key     func(req *http.Request) string // 限流的键
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time // 上次清理空闲令牌桶的时间
+}
+
+// rateLimitIdle 清理空闲令牌桶的间隔, 速率没有恢复的令牌桶空闲超过此时间也会被清理
+const rateLimitIdle = 5 * time.Minute
+
+// NewRateLimiter 创建限流器, rate 每秒请求数, burst 允许的突发请求数(最小为1)
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: burst, buckets: map[string]*tokenBucket{}, swept: time.Now()}
+}
+
+// KeyBy 自定义限流的键, 默认为请求的主机(包括端口)
+func (l *RateLimiter) KeyBy(key func(req *http.Request) string) *RateLimiter {
+	l.key = key
+	return l
+}
+
+// RateLimit 使用限流器
+func RateLimit(l *RateLimiter) Option {
+	return Transport(l.Mw)
+}
+
+// Mw 请求中间件, 发送前等待令牌, 收到 429 降低速率
+func (l *RateLimiter) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		key := l.keyOf(req)
+		if err := l.Wait(req.Context(), key); err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(req)
+		if err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				after, _ := ParseRetryAfter(resp.Header.Get(HeaderRetryAfter), time.Now())
+				l.Penalize(key, after)
+			} else {
+				l.recover(key)
+			}
+		}
+		return resp, err
+	})
+}
+
+// Wait 等待一个令牌, ctx 结束则返回错误
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	b := l.bucket(key)
+	wait := b.reserve(time.Now(), l.burst)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Penalize 降低速率为当前的一半(不低于初始速率的1/16), 并暂停 pause 时长, 暂停结束后从头积累令牌
+func (l *RateLimiter) Penalize(key string, pause time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(key)
+	now := time.Now()
+	b.refill(now, l.burst)
+	if b.rate /= 2; b.rate < l.rate/16 {
+		b.rate = l.rate / 16
+	}
+	if until := now.Add(pause); until.After(b.until) {
+		b.until = until
+	}
+	// 暂停期间不补充令牌, 暂停结束时最多立即放行一个请求, 之后的请求按速率间隔
+	if b.until.After(b.last) {
+		b.last = b.until
+	}
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+}
+
+// Rate 当前的速率
+func (l *RateLimiter) Rate(key string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		return b.rate
+	}
+	return l.rate
+}
+
+// recover 逐步恢复速率
+func (l *RateLimiter) recover(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok && b.rate < l.rate {
+		b.refill(time.Now(), l.burst)
+		if b.rate += l.rate / 16; b.rate > l.rate {
+			b.rate = l.rate
+		}
+	}
+}
+
+func (l *RateLimiter) keyOf(req *http.Request) string {
+	if l.key != nil {
+		return l.key(req)
+	}
+	return req.URL.Host
+}
+
+func (l *RateLimiter) bucket(key string) *tokenBucket {
+	if now := time.Now(); now.Sub(l.swept) >= rateLimitIdle {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{rate: l.rate, tokens: float64(l.burst), last: time.Now()}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// sweep 清理空闲的令牌桶: 令牌已补满, 没有暂停, 并且速率已恢复或者空闲超过 rateLimitIdle
+func (l *RateLimiter) sweep(now time.Time) {
+	l.swept = now
+	for key, b := range l.buckets {
+		idle := now.Sub(b.last)
+		b.refill(now, l.burst)
+		if b.tokens >= float64(l.burst) && !b.until.After(now) && (b.rate >= l.rate || idle >= rateLimitIdle) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	rate   float64   // 当前速率
+	tokens float64   // 剩余令牌, 为负表示已预约
+	last   time.Time // 上次补充令牌的时间, 暂停时为暂停结束的时间
+	until  time.Time // 暂停到此时间
+}
+
+// refill 按时间补充令牌
+func (b *tokenBucket) refill(now time.Time, burst int) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		if b.tokens += elapsed.Seconds() * b.rate; b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+		b.last = now
+	}
+}
+
+// reserve 预约一个令牌, 返回需要等待的时间, 暂停期间为剩余的暂停时间加上暂停结束后等待令牌的时间
+func (b *tokenBucket) reserve(now time.Time, burst int) time.Duration {
+	b.refill(now, burst)
+	b.tokens--
+
+	var wait time.Duration
+	if pause := b.until.Sub(now); pause > 0 {
+		wait = pause
+	}
+	if b.tokens < 0 {
+		wait += time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	return wait
+}
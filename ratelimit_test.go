@@ -0,0 +1,73 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			rw.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer closer()
+
+	limiter := NewRateLimiter(20, 1)
+	s := NewSession(RateLimit(limiter)).BaseURL(addr)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := s.New(context.TODO()).Url("/").Process(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("rate limit not applied: %s", elapsed)
+	}
+
+	u, _ := url.Parse(addr)
+	if err := s.New(context.TODO()).Url("/busy").Process(nil); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{limiter.Rate(u.Host), float64(10)}})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	limiter.Penalize(u.Host, time.Minute)
+	if err := s.New(ctx).Url("/").Process(nil); err == nil {
+		t.Fatal("want context error while paused")
+	}
+}
+
+func TestRateLimitPause(t *testing.T) {
+	limiter := NewRateLimiter(100, 5)
+	limiter.Penalize("host", 50*time.Millisecond)
+
+	// 暂停结束后按减半的速率(50/s)间隔放行, 而不是同时放行
+	b, now := limiter.buckets["host"], time.Now()
+	var waits []time.Duration
+	for i := 0; i < 3; i++ {
+		waits = append(waits, b.reserve(now, limiter.burst).Round(10*time.Millisecond))
+	}
+	eq(t, [][2]any{{waits[0], 50 * time.Millisecond}, {waits[1], 70 * time.Millisecond}, {waits[2], 90 * time.Millisecond}})
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	limiter := NewRateLimiter(100, 1)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := limiter.Wait(context.TODO(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	limiter.Penalize("c", time.Hour)
+
+	limiter.mu.Lock()
+	limiter.sweep(time.Now().Add(time.Second))
+	_, paused := limiter.buckets["c"]
+	eq(t, [][2]any{{len(limiter.buckets), 1}, {paused, true}})
+	limiter.mu.Unlock()
+}
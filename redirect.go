@@ -0,0 +1,106 @@
+package urlx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// HeaderLocation 跳转地址
+const HeaderLocation = "Location"
+
+// ErrRedirectBlocked 跳转被策略禁止
+var ErrRedirectBlocked = errors.New("redirect blocked")
+
+// defaultMaxRedirects 默认最多跟随的跳转次数, 比 http.Client 多一次(http.Client 在第10次跳转时停止, 最多跟随9次)
+const defaultMaxRedirects = 10
+
+// sensitiveHeaders 跨主机跳转时默认删除的请求头
+var sensitiveHeaders = []string{HeaderAuthorization, HeaderWWWAuthenticate, "Cookie", "Cookie2"}
+
+// RedirectPolicy 跳转策略, 被禁止的跳转返回 ErrRedirectBlocked
+type RedirectPolicy struct {
+	Max             int      // 最多跟随的跳转次数, 0 为默认的10次, 小于0不跳转, 直接处理跳转响应
+	SameHost        bool     // 只允许跳转到与第一个请求相同的主机
+	NoDowngrade     bool     // 禁止从 HTTPS 跳转到 HTTP
+	ResendSensitive bool     // 跨主机跳转时仍然发送敏感请求头, 默认删除
+	Sensitive       []string // 额外的敏感请求头, 如 X-Api-Key, Authorization 和 Cookie 总是敏感的
+}
+
+// RedirectWith 设置跳转策略, 替换客户端的 CheckRedirect
+func (c *Request) RedirectWith(policy RedirectPolicy) *Request {
+	c.redirect = &policy
+	return c
+}
+
+// Redirect 设置跳转策略
+func Redirect(policy RedirectPolicy) Option {
+	return func(r *Request) error {
+		r.RedirectWith(policy)
+		return nil
+	}
+}
+
+// RedirectWith 设置默认的跳转策略
+func (s *Session) RedirectWith(policy RedirectPolicy) *Session {
+	return s.update(func(tpl *Request) { tpl.RedirectWith(policy) })
+}
+
+// check 实现 http.Client 的 CheckRedirect
+func (p *RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if p.Max < 0 {
+		return http.ErrUseLastResponse
+	}
+
+	max := p.Max
+	if max == 0 {
+		max = defaultMaxRedirects
+	}
+	if len(via) > max {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrRedirectBlocked, max)
+	}
+
+	first, prev := via[0], via[len(via)-1]
+	if p.SameHost && req.URL.Host != first.URL.Host {
+		return fmt.Errorf("%w: cross host %s -> %s", ErrRedirectBlocked, first.URL.Host, req.URL.Host)
+	}
+	if p.NoDowngrade && prev.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return fmt.Errorf("%w: https -> http %s", ErrRedirectBlocked, req.URL.Redacted())
+	}
+
+	if req.URL.Host != first.URL.Host {
+		for _, names := range [][]string{sensitiveHeaders, p.Sensitive} {
+			for _, name := range names {
+				if values := first.Header.Values(name); p.ResendSensitive && len(values) > 0 {
+					req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+				} else {
+					req.Header.Del(name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RedirectHop 跳转链中的一次跳转
+type RedirectHop struct {
+	URL        *url.URL       // 请求地址
+	StatusCode int            // 跳转的状态码
+	Location   string         // 跳转到的地址
+	Cookies    []*http.Cookie // 跳转响应设置的 Cookie
+}
+
+// Redirects 响应经过的跳转链, 按跳转顺序排列, 没有跳转返回空, 最终地址为 resp.Request.URL
+func Redirects(resp *http.Response) []RedirectHop {
+	var hops []RedirectHop
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		prev := req.Response
+		hop := RedirectHop{StatusCode: prev.StatusCode, Location: prev.Header.Get(HeaderLocation), Cookies: prev.Cookies()}
+		if prev.Request != nil {
+			hop.URL = prev.Request.URL
+		}
+		hops = append([]RedirectHop{hop}, hops...)
+	}
+	return hops
+}
@@ -0,0 +1,63 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	other, closeOther := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.Header.Get(HeaderAuthorization) + "|" + r.Header.Get("X-Api-Key")))
+	}))
+	defer closeOther()
+
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.SetCookie(rw, &http.Cookie{Name: "step", Value: "a"})
+			http.Redirect(rw, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(rw, r, other+"/c", http.StatusMovedPermanently)
+		}
+	}))
+	defer closer()
+
+	get := func(policy RedirectPolicy) (body string, hops []RedirectHop, err error) {
+		err = New(context.TODO(), Redirect(policy)).Url(addr+"/a").
+			HeaderWith(BearerAuth("t"), HeaderSet("X-Api-Key", "k")).
+			Process(func(resp *http.Response) error {
+				hops = Redirects(resp)
+				data, err := io.ReadAll(resp.Body)
+				body = string(data)
+				return err
+			})
+		return
+	}
+
+	body, hops, err := get(RedirectPolicy{Sensitive: []string{"X-Api-Key"}})
+	eq(t, [][2]any{{err, nil}, {body, "|"}, {len(hops), 2}})
+	eq(t, [][2]any{
+		{hops[0].URL.Path, "/a"}, {hops[0].StatusCode, http.StatusFound}, {hops[0].Location, "/b"},
+		{hops[0].Cookies[0].Value, "a"}, {hops[1].StatusCode, http.StatusMovedPermanently}, {hops[1].Location, other + "/c"},
+	})
+
+	body, _, err = get(RedirectPolicy{ResendSensitive: true})
+	eq(t, [][2]any{{err, nil}, {body, "Bearer t|k"}})
+
+	_, _, err = get(RedirectPolicy{SameHost: true})
+	eq(t, [][2]any{{errors.Is(err, ErrRedirectBlocked), true}})
+
+	_, _, err = get(RedirectPolicy{Max: 1})
+	eq(t, [][2]any{{errors.Is(err, ErrRedirectBlocked), true}})
+
+	// Max 为跟随的跳转次数, 两次跳转刚好跟随
+	_, hops, err = get(RedirectPolicy{Max: 2})
+	eq(t, [][2]any{{err, nil}, {len(hops), 2}})
+
+	body, hops, err = get(RedirectPolicy{Max: -1})
+	eq(t, [][2]any{{err, nil}, {strings.Contains(body, "/b"), true}, {len(hops), 0}})
+}
@@ -32,20 +32,35 @@ type (
 type Request struct {
 	ctx     context.Context        // Context
 	options []func(*Request) error // options
+	logger  Logger                 // 日志记录
 
 	// request fields
-	method    string         // 接口请求方法
-	url       string         // 请求地址
-	query     string         // 请求链接参数
-	buildBody Body           // 请求内容
-	headers   []HeaderOption // 请求头处理
+	method    string            // 接口请求方法
+	base      string            // 基础地址, 相对地址以此为基准
+	url       string            // 请求地址
+	params    map[string]string // 路径参数
+	query     string            // 请求链接参数, 原样拼接
+	queries   []QueryOption     // 请求链接参数
+	buildBody Body              // 请求内容
+	bodyLimit int64             // 无法回退的请求内容最多缓存的字节数
+	headers   []HeaderOption    // 请求头处理
+	cookies   []*http.Cookie    // 请求Cookie, 丢弃无效的字节时记录日志
 
 	// response fields
 	beforeMw []ProcessMw // 中间件
 
 	// client fields
-	tryTimes []time.Duration // 重试时间和时机
-	client   *http.Client    // client
+	transports      []RequestMw     // 请求中间件
+	retry           RetryPolicy     // 重试策略
+	attemptTimeout  time.Duration   // 单次请求超时
+	attemptWithBody bool            // 单次请求超时是否包括读取响应内容
+	hedgeDelay      time.Duration   // 对冲请求的间隔
+	hedgeMax        int             // 对冲请求最多同时发送的数量
+	redirect        *RedirectPolicy // 跳转策略
+	proxy           *url.URL        // 代理
+	client          *http.Client    // client
+	jar             http.CookieJar  // Cookie容器
+	jarSet          bool            // 是否设置了Cookie容器
 }
 
 // New 以一些选项开始初始化请求器
@@ -53,6 +68,31 @@ func New(ctx context.Context, options ...Option) *Request {
 	return (&Request{ctx: ctx}).With(options...)
 }
 
+// Clone 复制一份请求, 副本的修改不会影响原请求, 可以将一个请求作为模板派生多个请求
+func (c *Request) Clone() *Request {
+	r := *c
+	r.options = append([]Option(nil), c.options...)
+	r.queries = append([]QueryOption(nil), c.queries...)
+	r.headers = append([]HeaderOption(nil), c.headers...)
+	r.cookies = append([]*http.Cookie(nil), c.cookies...)
+	if c.params != nil {
+		r.params = make(map[string]string, len(c.params))
+		for name, value := range c.params {
+			r.params[name] = value
+		}
+	}
+	r.beforeMw = append([]ProcessMw(nil), c.beforeMw...)
+	r.transports = append([]RequestMw(nil), c.transports...)
+	return &r
+}
+
+// WithContext 复制一份请求并使用新的 Context
+func (c *Request) WithContext(ctx context.Context) *Request {
+	r := c.Clone()
+	r.ctx = ctx
+	return r
+}
+
 /*请求公共设置*/
 
 // With 增加选项
@@ -73,7 +113,13 @@ func (c *Request) Url(url string) *Request {
 	return c
 }
 
-// Query 设置请求Query参数
+// BaseURL 设置基础地址, 相对的请求地址以此为基准解析
+func (c *Request) BaseURL(base string) *Request {
+	c.base = base
+	return c
+}
+
+// Query 设置请求Query参数, 如 a=1&b=2, 原样拼接到链接后面
 func (c *Request) Query(query string) *Request {
 	c.query = query
 	return c
@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -126,6 +128,27 @@ func TestHeader(t *testing.T) {
 	}
 }
 
+func TestCookieWarn(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.Header.Get(HeaderRequestCookie)))
+	}))
+	defer closer()
+
+	var warns []string
+	logger := LoggerFunc(func(ctx context.Context, e LogEvent) {
+		if e.Kind == LogWarn {
+			warns = append(warns, e.Err.Error())
+		}
+	})
+	data, err := New(context.TODO()).Url(addr).UseLogger(logger).
+		Cookie(&http.Cookie{Name: "a", Value: "x\"y"}, &http.Cookie{Name: "b", Value: "ok"}).
+		Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "a=xy; b=ok"}, {strings.Join(warns, ","), `net/http: invalid byte '"' in Cookie.Value; dropping invalid bytes`}})
+}
+
 func TestTry(t *testing.T) {
 	addr, closer := mockHTTPServer(nil)
 	defer closer()
@@ -158,3 +181,32 @@ func TestDownload(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClone(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("X-Base") + r.Header.Get("X-Item")))
+	}))
+	defer closer()
+
+	base := New(context.TODO(), CookieEnabled()).HeaderWith(HeaderSet("X-Base", "b")).TryAt(time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := "/" + strconv.Itoa(i)
+			data, err := base.Clone().Url(addr + path).HeaderWith(HeaderSet("X-Item", strconv.Itoa(i))).Bytes()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want := "GET " + path + " b" + strconv.Itoa(i); string(data) != want {
+				t.Errorf("%q != %q", data, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	eq(t, [][2]any{{len(base.headers), 1}, {base.method, ""}, {base.client == nil, true}})
+}
@@ -2,12 +2,9 @@ package urlx
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"io"
-	"log"
-	"net"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -38,8 +35,17 @@ func Status(processStatus func(status int) Process) ProcessMw {
 	}
 }
 
-// Process 处理响应
+// Process 处理响应, 在副本上执行, 不会修改当前请求, 可以并发调用
 func (c *Request) Process(process Process) error {
+	r := c.Clone()
+	if err := r.prepare(); err != nil {
+		return err
+	}
+	return r.execute(process)
+}
+
+// prepare 应用选项并设置默认值
+func (c *Request) prepare() error {
 	if c.client == nil {
 		c.client = &http.Client{}
 	}
@@ -50,6 +56,22 @@ func (c *Request) Process(process Process) error {
 		}
 	}
 
+	if c.jarSet && c.client.Jar != c.jar || len(c.transports) > 0 || c.redirect != nil || c.proxy != nil {
+		client := *c.client
+		if c.jarSet {
+			client.Jar = c.jar
+		}
+		if c.redirect != nil {
+			client.CheckRedirect = c.redirect.check
+		}
+		rt, err := c.roundTripper()
+		if err != nil {
+			return err
+		}
+		client.Transport = rt
+		c.client = &client
+	}
+
 	if c.ctx == nil {
 		c.ctx = context.Background()
 	}
@@ -58,66 +80,145 @@ func (c *Request) Process(process Process) error {
 		c.method = http.MethodGet
 	}
 
-	requestUrl := c.url
-	if c.query != "" {
-		if strings.Contains(requestUrl, "?") {
-			requestUrl += "&" + c.query
-		} else {
-			requestUrl += "?" + c.query
-		}
-	}
-
 	if c.buildBody == nil {
 		c.buildBody = func() (contentType string, body io.Reader, err error) { return "", nil, nil }
 	}
+	return nil
+}
+
+// execute 发送请求并处理响应
+func (c *Request) execute(process Process) error {
+	requestUrl, err := c.buildURL()
+	if err != nil {
+		return err
+	}
+
+	contentType, rawBody, err := c.buildBody()
+	if err != nil {
+		return err
+	}
+	body, err := newReplayBody(rawBody, c.bodyLimit)
+	if err != nil {
+		return err
+	}
+	defer closes(body)
 
-	var resp *http.Response
-	for i := 0; i < len(c.tryTimes)+1; i++ {
-		contentType, body, err := c.buildBody()
+	if process == nil {
+		process = ProcessNil
+	}
+	for _, before := range c.beforeMw {
+		process = before(process)
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(requestUrl, contentType, body)
 		if err != nil {
+			if attempt > 1 {
+				err = fmt.Errorf("第%d次请求: %w", attempt, err)
+			}
 			return err
 		}
 
-		req, err := http.NewRequestWithContext(c.ctx, c.method, requestUrl, body)
-		if err != nil {
-			return err
+		sent := time.Now()
+		resp, timer, err := c.do(req)
+		event := LogEvent{Kind: LogAttempt, Attempt: attempt, Method: c.method, URL: req.URL.Redacted(), Latency: time.Since(sent), Err: err}
+		if resp != nil {
+			event.Status = resp.StatusCode
 		}
+		c.log(event)
 
-		if contentType != "" {
-			req.Header.Set(HeaderContentType, contentType)
+		if c.ctx.Err() != nil {
+			if err == nil {
+				closes(resp.Body)
+				err = c.ctx.Err()
+			}
+			return err
 		}
 
-		for _, headerOption := range c.headers {
-			headerOption(req.Header)
+		wait, retry := c.retry.next(attempt, start, resp, err)
+		switch {
+		case retry && resp != nil:
+			drain(resp.Body)
+		case !retry && err != nil:
+			event.Kind = LogGiveUp
+			c.log(event)
+			return err
+		case !retry:
+			// 读取响应内容超时, 处理器还没有读到内容时可以重试
+			if err = handle(resp, process); err == nil || !timer.Expired() || timer.Consumed() || c.ctx.Err() != nil {
+				return err
+			}
+			event.Err = err
+			if wait, retry = c.retry.next(attempt, start, nil, err); !retry {
+				event.Kind = LogGiveUp
+				c.log(event)
+				return err
+			}
 		}
 
-		if resp, err = c.client.Do(req); err != nil {
-			var ne net.Error
-			if i < len(c.tryTimes) && errors.As(err, &ne) {
-				log.Printf("第%d次出错: %v, %s后重试", i+1, err, c.tryTimes[i])
-				select {
-				case <-c.ctx.Done():
-					return err
-				case <-time.After(c.tryTimes[i]):
-					continue
-				}
+		event.Kind, event.Backoff = LogRetry, wait
+		c.log(event)
+
+		select {
+		case <-c.ctx.Done():
+			if err == nil {
+				err = c.ctx.Err()
 			}
-			log.Printf("第%d次出错: %v, 返回错误", i+1, err)
 			return err
+		case <-time.After(wait):
 		}
-		break
 	}
+}
 
-	body := resp.Body
-	defer closes(body)
+// newRequest 构造一次请求
+func (c *Request) newRequest(requestUrl, contentType string, body *replayBody) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.ctx, c.method, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	if process == nil {
-		process = ProcessNil
+	if err = body.apply(req); err != nil {
+		return nil, err
 	}
-	for _, before := range c.beforeMw {
-		process = before(process)
+
+	if contentType != "" {
+		req.Header.Set(HeaderContentType, contentType)
 	}
 
+	for _, headerOption := range c.headers {
+		headerOption(req.Header)
+	}
+	addCookies(req.Header, c.cookies, func(err error) {
+		c.log(LogEvent{Kind: LogWarn, Method: req.Method, URL: req.URL.Redacted(), Err: err})
+	})
+	return req, nil
+}
+
+// build 构造请求但不发送, 用于导出和签名, 需要先 prepare, 使用完成后调用 release
+func (c *Request) build() (req *http.Request, release func(), err error) {
+	requestUrl, err := c.buildURL()
+	if err != nil {
+		return nil, nil, err
+	}
+	contentType, rawBody, err := c.buildBody()
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := newReplayBody(rawBody, c.bodyLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req, err = c.newRequest(requestUrl, contentType, body); err != nil {
+		closes(body)
+		return nil, nil, err
+	}
+	return req, func() { closes(body) }, nil
+}
+
+// handle 处理响应并关闭
+func handle(resp *http.Response, process Process) error {
+	defer closes(resp.Body)
 	return process(resp)
 }
 
@@ -130,13 +231,17 @@ func (c *Request) Bytes() (data []byte, err error) {
 	return
 }
 
+// drain 读取剩余内容后关闭, 以便复用连接
+func drain(body io.ReadCloser) {
+	if body != nil {
+		_, _ = io.CopyN(io.Discard, body, 4<<10)
+		closes(body)
+	}
+}
+
 //closes 静默关闭 io.Closer
-func closes(closer io.Closer, errPrintPrefix ...string) {
+func closes(closer io.Closer) {
 	if closer != nil {
-		if err := closer.Close(); err != nil {
-			if len(errPrintPrefix) > 0 && errPrintPrefix[0] != "" {
-				log.Printf("「%s」 %s", errPrintPrefix, err)
-			}
-		}
+		_ = closer.Close()
 	}
 }
@@ -0,0 +1,190 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const HeaderRetryAfter = "Retry-After"
+
+// DefaultRetryMaxDelay 默认单次重试的最长等待时间, 用于直接设置的 RetryPolicy, TryAt 和 TryIdempotent 不限制
+const DefaultRetryMaxDelay = time.Minute
+
+// 一些重试相关方法的定义
+type (
+	RetryCheck = func(resp *http.Response, err error) bool // 是否需要重试, resp 和 err 有一个不为空
+	Backoff    = func(attempt int) time.Duration           // 第 attempt(从1开始) 次失败后的等待时间
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数(包含首次请求), 小于等于1则不重试
+	Backoff     Backoff       // 退避时间, 为空则立即重试
+	Jitter      float64       // 抖动比例(0~1), 在退避时间上随机增减
+	MaxElapsed  time.Duration // 最长总耗时, 超出后不再重试, 0不限制
+	MaxDelay    time.Duration // 单次最长等待时间, 退避时间超出时取该值, Retry-After 超出时不再重试, 0为 DefaultRetryMaxDelay, 小于0不限制
+	Check       RetryCheck    // 是否需要重试, 为空则使用 RetryTemporary
+}
+
+// RetryWith 设置重试策略
+func (c *Request) RetryWith(policy RetryPolicy) *Request {
+	c.retry = policy
+	return c
+}
+
+// Retry 设置重试策略
+func Retry(policy RetryPolicy) Option {
+	return func(r *Request) error {
+		r.RetryWith(policy)
+		return nil
+	}
+}
+
+// next 判断第 attempt 次请求之后是否需要重试以及等待的时间
+func (p RetryPolicy) next(attempt int, start time.Time, resp *http.Response, err error) (wait time.Duration, retry bool) {
+	if attempt >= p.MaxAttempts {
+		return
+	}
+
+	check := p.Check
+	if check == nil {
+		check = RetryTemporary
+	}
+	if !check(resp, err) {
+		return
+	}
+
+	if p.Backoff != nil {
+		wait = p.Backoff(attempt)
+	}
+	if p.Jitter > 0 && wait > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		wait += time.Duration((rand.Float64()*2 - 1) * jitter * float64(wait))
+	}
+
+	maxDelay := p.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+	if maxDelay > 0 && wait > maxDelay {
+		wait = maxDelay
+	}
+
+	if resp != nil {
+		if after, ok := ParseRetryAfter(resp.Header.Get(HeaderRetryAfter), time.Now()); ok && after > wait {
+			if maxDelay > 0 && after > maxDelay {
+				return 0, false
+			}
+			wait = after
+		}
+	}
+
+	if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+		return 0, false
+	}
+	return wait, true
+}
+
+// RetryTemporary 临时的网络错误(不包括域名不存在, 地址无效等)或者 429, 502, 503, 504 时重试
+func RetryTemporary(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, ErrAttemptTimeout) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return true
+		}
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		// *url.Error 本身实现了 net.Error, 需要判断其包装的错误
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			return dnsErr.IsTimeout || dnsErr.IsTemporary
+		}
+		var ne net.Error
+		return errors.As(err, &ne)
+	}
+	return RetryStatus(http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout)(resp, err)
+}
+
+// RetryStatus 响应状态码为指定值时重试
+func RetryStatus(codes ...int) RetryCheck {
+	return func(resp *http.Response, err error) bool {
+		if resp == nil {
+			return false
+		}
+		for _, code := range codes {
+			if resp.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// BackoffDelays 按顺序使用给定的等待时间, 超出后使用最后一个
+func BackoffDelays(delays ...time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		if len(delays) == 0 {
+			return 0
+		}
+		if attempt > len(delays) {
+			attempt = len(delays)
+		}
+		return delays[attempt-1]
+	}
+}
+
+// BackoffConstant 固定等待时间
+func BackoffConstant(wait time.Duration) Backoff {
+	return func(int) time.Duration { return wait }
+}
+
+// BackoffExponential 指数退避, base * 2^(attempt-1), max 大于0时不超过 max
+func BackoffExponential(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		wait := base
+		for i := 1; i < attempt && wait > 0; i++ {
+			if wait *= 2; max > 0 && wait >= max {
+				return max
+			}
+		}
+		if max > 0 && wait > max {
+			wait = max
+		}
+		return wait
+	}
+}
+
+// ParseRetryAfter 解析 Retry-After, 支持秒数和 HTTP 日期两种格式
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value = strings.TrimSpace(value); value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
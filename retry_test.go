@@ -0,0 +1,130 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryStatus(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			rw.Header().Set(HeaderRetryAfter, "0")
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = rw.Write([]byte("ok"))
+	}))
+	defer closer()
+
+	var kinds []string
+	logger := LoggerFunc(func(ctx context.Context, e LogEvent) { kinds = append(kinds, e.Kind+":"+strconv.Itoa(e.Status)) })
+	data, err := New(context.TODO()).Url(addr).
+		RetryWith(RetryPolicy{MaxAttempts: 3, Backoff: BackoffConstant(time.Millisecond)}).
+		UseLogger(logger).
+		Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{
+		{string(data), "ok"},
+		{atomic.LoadInt32(&hits), int32(3)},
+		{strings.Join(kinds, ","), "attempt:503,retry:503,attempt:503,retry:503,attempt:200"},
+	})
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		rw.Header().Set(HeaderRetryAfter, "3600")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer closer()
+
+	var status int
+	err := New(context.TODO()).Url(addr).
+		RetryWith(RetryPolicy{MaxAttempts: 5, MaxElapsed: time.Second}).
+		Process(func(resp *http.Response) error { status = resp.StatusCode; return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{status, http.StatusTooManyRequests}, {atomic.LoadInt32(&hits), int32(1)}})
+}
+
+func TestRetryMaxDelay(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		rw.Header().Set(HeaderRetryAfter, "86400")
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer closer()
+
+	start := time.Now()
+	var status int
+	err := New(context.TODO()).Url(addr).RetryWith(RetryPolicy{MaxAttempts: 3, Backoff: BackoffConstant(time.Millisecond)}).
+		Process(func(resp *http.Response) error { status = resp.StatusCode; return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{status, http.StatusServiceUnavailable}, {atomic.LoadInt32(&hits), int32(1)}, {time.Since(start) < time.Second, true}})
+
+	wait, ok := RetryPolicy{MaxAttempts: 2, Backoff: BackoffConstant(time.Hour)}.next(1, time.Now(), nil, io.EOF)
+	eq(t, [][2]any{{wait, DefaultRetryMaxDelay}, {ok, true}})
+	wait, ok = RetryPolicy{MaxAttempts: 2, Backoff: BackoffConstant(time.Hour), MaxDelay: -1}.next(1, time.Now(), nil, io.EOF)
+	eq(t, [][2]any{{wait, time.Hour}, {ok, true}})
+
+	// TryAt 和 TryIdempotent 保持原有行为, 不限制单次等待时间
+	wait, ok = New(nil).TryAt(2*time.Minute).retry.next(1, time.Now(), nil, io.EOF)
+	eq(t, [][2]any{{wait, 2 * time.Minute}, {ok, true}})
+	r := New(nil)
+	_ = TryIdempotent(time.Millisecond, 1)(r)
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{HeaderRetryAfter: {"86400"}}}
+	wait, ok = r.retry.next(1, time.Now(), resp, nil)
+	eq(t, [][2]any{{wait, 24 * time.Hour}, {ok, true}})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := ParseRetryAfter("120", now)
+	eq(t, [][2]any{{d, 2 * time.Minute}, {ok, true}})
+	d, ok = ParseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	eq(t, [][2]any{{d, time.Minute}, {ok, true}})
+	_, ok = ParseRetryAfter("soon", now)
+	eq(t, [][2]any{{ok, false}})
+}
+
+func TestRetryTemporaryDialError(t *testing.T) {
+	for _, dialErr := range []error{
+		&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true},
+		errors.New("dial: blocked by policy"),
+	} {
+		var dials int32
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return nil, dialErr
+			},
+		}}
+
+		err := New(context.TODO()).Url("http://example.invalid/").UseClient(client).
+			RetryWith(RetryPolicy{MaxAttempts: 3}).
+			Process(func(*http.Response) error { return nil })
+		if err == nil {
+			t.Fatal("expected dial error")
+		}
+		eq(t, [][2]any{{atomic.LoadInt32(&dials), int32(1)}, {RetryTemporary(nil, err), false}})
+	}
+
+	eq(t, [][2]any{{RetryTemporary(nil, &url.Error{Op: "Get", URL: "http://x/", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}), true}})
+}
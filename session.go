@@ -0,0 +1,86 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+	"time"
+)
+
+// Session 会话, 派生的请求共享同一个客户端、Cookie容器、基础地址和默认设置
+type Session struct {
+	mu  sync.RWMutex
+	tpl *Request // 派生请求的模板
+}
+
+// NewSession 创建会话, 默认开启 Cookie
+func NewSession(options ...Option) *Session {
+	jar, _ := cookiejar.New(nil)
+	tpl := &Request{client: &http.Client{Jar: jar}, jar: jar, jarSet: true}
+	return &Session{tpl: tpl.With(options...)}
+}
+
+// New 派生一个请求
+func (s *Session) New(ctx context.Context) *Request {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tpl.WithContext(ctx)
+}
+
+// update 修改模板
+func (s *Session) update(apply func(tpl *Request)) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apply(s.tpl)
+	return s
+}
+
+// BaseURL 设置基础地址, 派生请求的相对地址以此为基准解析
+func (s *Session) BaseURL(base string) *Session {
+	return s.update(func(tpl *Request) { tpl.BaseURL(base) })
+}
+
+// With 增加默认选项
+func (s *Session) With(options ...Option) *Session {
+	return s.update(func(tpl *Request) { tpl.With(options...) })
+}
+
+// HeaderWith 增加默认请求头
+func (s *Session) HeaderWith(options ...HeaderOption) *Session {
+	return s.update(func(tpl *Request) { tpl.HeaderWith(options...) })
+}
+
+// ProcessWith 增加默认的响应预处理
+func (s *Session) ProcessWith(mws ...ProcessMw) *Session {
+	return s.update(func(tpl *Request) { tpl.ProcessWith(mws...) })
+}
+
+// RetryWith 设置默认的重试策略
+func (s *Session) RetryWith(policy RetryPolicy) *Session {
+	return s.update(func(tpl *Request) { tpl.RetryWith(policy) })
+}
+
+// TryAt 设置默认的失败重试等待时间
+func (s *Session) TryAt(times ...time.Duration) *Session {
+	return s.update(func(tpl *Request) { tpl.TryAt(times...) })
+}
+
+// UseClient 使用自定义的HTTP客户端, 会话的Cookie容器保持不变
+func (s *Session) UseClient(client *http.Client) *Session {
+	return s.update(func(tpl *Request) { tpl.UseClient(client) })
+}
+
+// Client 会话使用的HTTP客户端
+func (s *Session) Client() *http.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tpl.client
+}
+
+// Jar 会话的Cookie容器
+func (s *Session) Jar() http.CookieJar {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tpl.jar
+}
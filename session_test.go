@@ -0,0 +1,39 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/login" {
+			http.SetCookie(rw, &http.Cookie{Name: "sid", Value: "s1", Path: "/"})
+			return
+		}
+		sid, _ := r.Cookie("sid")
+		if sid == nil {
+			sid = &http.Cookie{}
+		}
+		_, _ = rw.Write([]byte(r.URL.Path + " " + sid.Value + " " + r.Header.Get("X-App")))
+	}))
+	defer closer()
+
+	s := NewSession().BaseURL(addr + "/api").HeaderWith(HeaderSet("X-App", "urlx"))
+	if err := s.New(context.TODO()).Url("login").Process(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.New(context.TODO()).Url("users/{id}").PathParam("id", "1").UseClient(&http.Client{}).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "/api/users/1 s1 urlx"}})
+
+	data, err = s.New(context.TODO()).Url(addr + "/other").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "/other s1 urlx"}})
+}
@@ -0,0 +1,379 @@
+package urlx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* 请求签名, 签名中间件应在其它修改请求的中间件之前添加(位于内层), 否则签名后的修改会使签名失效 */
+
+const (
+	HeaderAmzDate          = "X-Amz-Date"
+	HeaderAmzContentSha256 = "X-Amz-Content-Sha256"
+	HeaderAmzSecurityToken = "X-Amz-Security-Token"
+
+	awsAlgorithm       = "AWS4-HMAC-SHA256"
+	awsTimeFormat      = "20060102T150405Z"
+	awsUnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// AWSSigner AWS Signature V4 签名, 也适用于 MinIO 等 S3 兼容的服务
+type AWSSigner struct {
+	AccessKey    string // Access Key ID
+	SecretKey    string // Secret Access Key
+	SessionToken string // 临时凭证的会话令牌, 可选
+	Region       string // 区域, 如 us-east-1
+	Service      string // 服务, 如 s3
+
+	// UnsignedPayload 不计算请求内容的摘要, 使用 UNSIGNED-PAYLOAD(仅 S3 支持), 适用于无法重新读取的大文件上传
+	UnsignedPayload bool
+
+	// Expires 大于0时中间件使用预签名链接(签名在 Query 参数中)代替 Authorization 请求头
+	Expires time.Duration
+
+	now func() time.Time // 当前时间, 测试用
+}
+
+// SignAWS 使用 AWS Signature V4 签名每次发送的请求
+func SignAWS(s *AWSSigner) Option {
+	return Transport(s.Mw)
+}
+
+// Mw 请求中间件, 签名每次发送的请求(包括重试和跳转)
+func (s *AWSSigner) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		signed := req.Clone(req.Context())
+		if s.Expires > 0 {
+			if _, err := s.presign(signed, s.Expires, s.time()); err != nil {
+				return nil, err
+			}
+		} else if err := s.sign(signed, s.time()); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(signed)
+	})
+}
+
+// Sign 在请求头中签名, 设置 Authorization 和 X-Amz-Date, 请求内容通过 GetBody 读取, 不会被消耗
+func (s *AWSSigner) Sign(req *http.Request) error {
+	return s.sign(req, s.time())
+}
+
+// Presign 在链接中签名, 返回有效期为 expires 的预签名链接, 请求的链接同时被修改
+func (s *AWSSigner) Presign(req *http.Request, expires time.Duration) (string, error) {
+	return s.presign(req, expires, s.time())
+}
+
+// Presign 构造请求并返回预签名链接, 请求不会发送, 常用于生成 S3 的下载或上传链接
+func (c *Request) Presign(s *AWSSigner, expires time.Duration) (string, error) {
+	r := c.Clone()
+	if err := r.prepare(); err != nil {
+		return "", err
+	}
+	req, release, err := r.build()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return s.Presign(req, expires)
+}
+
+// time 签名时间
+func (s *AWSSigner) time() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// scope 凭证范围
+func (s *AWSSigner) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+// sign 请求头签名
+func (s *AWSSigner) sign(req *http.Request, t time.Time) error {
+	payload, err := s.payloadHash(req, false)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Del(HeaderAuthorization)
+	req.Header.Set(HeaderAmzDate, t.Format(awsTimeFormat))
+	if s.SessionToken != "" {
+		req.Header.Set(HeaderAmzSecurityToken, s.SessionToken)
+	}
+	if s.Service == "s3" {
+		req.Header.Set(HeaderAmzContentSha256, payload)
+	}
+
+	signedHeaders, canonicalHeaders := awsCanonicalHeaders(req)
+	signature := s.signature(t, awsCanonicalRequest(req, s.Service, req.URL.Query(), signedHeaders, canonicalHeaders, payload))
+	req.Header.Set(HeaderAuthorization, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, s.AccessKey, s.scope(t), signedHeaders, signature))
+	return nil
+}
+
+// presign 链接签名
+func (s *AWSSigner) presign(req *http.Request, expires time.Duration, t time.Time) (string, error) {
+	payload, err := s.payloadHash(req, true)
+	if err != nil {
+		return "", err
+	}
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", awsAlgorithm)
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(awsTimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	if s.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	query.Del("X-Amz-Signature")
+
+	signedHeaders, canonicalHeaders := awsCanonicalHeaders(req)
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	signature := s.signature(t, awsCanonicalRequest(req, s.Service, query, signedHeaders, canonicalHeaders, payload))
+
+	req.URL.RawQuery = awsCanonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return req.URL.String(), nil
+}
+
+// payloadHash 请求内容的 SHA256 摘要, S3 的预签名链接不包含请求内容
+func (s *AWSSigner) payloadHash(req *http.Request, presign bool) (string, error) {
+	if s.UnsignedPayload || presign && s.Service == "s3" {
+		return awsUnsignedPayload, nil
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+	return hashHex(sha256.New, body), nil
+}
+
+// signature 计算签名
+func (s *AWSSigner) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		awsAlgorithm,
+		t.Format(awsTimeFormat),
+		s.scope(t),
+		hashHex(sha256.New, []byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{t.Format("20060102"), s.Region, s.Service, "aws4_request"} {
+		key = hmacSum(sha256.New, key, []byte(part))
+	}
+	return hex.EncodeToString(hmacSum(sha256.New, key, []byte(stringToSign)))
+}
+
+// awsIgnoredHeaders 不参与签名的请求头, 它们可能被代理或传输层修改
+var awsIgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+	"connection":      true,
+}
+
+// awsCanonicalRequest 规范请求
+func awsCanonicalRequest(req *http.Request, service string, query url.Values, signedHeaders, canonicalHeaders, payload string) string {
+	path := awsEscapePath(req.URL)
+	// S3 以外的服务对路径再编码一次
+	if service != "s3" {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			segments[i] = awsEscape(segment)
+		}
+		path = strings.Join(segments, "/")
+	}
+	return strings.Join([]string{req.Method, path, awsCanonicalQuery(query), canonicalHeaders, signedHeaders, payload}, "\n")
+}
+
+// awsEscapePath 按段对路径进行 RFC 3986 编码, 并设置 RawPath 使发送的路径与签名的一致
+func awsEscapePath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segments[i] = awsEscape(segment)
+	}
+	u.RawPath = strings.Join(segments, "/")
+	return u.RawPath
+}
+
+// awsCanonicalHeaders 签名的请求头名称列表和规范请求头
+func awsCanonicalHeaders(req *http.Request) (signedHeaders, canonicalHeaders string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string][]string{"host": {host}}
+	for key, values := range req.Header {
+		if name := strings.ToLower(key); !awsIgnoredHeaders[name] {
+			headers[name] = append(headers[name], values...)
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		values := make([]string, len(headers[name]))
+		for i, value := range headers[name] {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		sb.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return strings.Join(names, ";"), sb.String()
+}
+
+// awsCanonicalQuery 按名称和值排序并编码的 Query 参数
+func awsCanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape RFC 3986 编码, 除 A-Za-z0-9-_.~ 外全部编码
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+/* 通用 HMAC 签名 */
+
+// HMACSigner 通用的 HMAC 签名, 按 Canonicalize 构造待签名字符串, 签名写入请求头
+type HMACSigner struct {
+	KeyID  string           // 密钥标识
+	Secret []byte           // 密钥
+	Hash   func() hash.Hash // 摘要算法, 默认 SHA256
+
+	// Canonicalize 构造待签名字符串, body 为请求内容, 默认 CanonicalHMAC
+	Canonicalize func(req *http.Request, body []byte) (string, error)
+	// Encode 签名编码, 默认 base64
+	Encode func(sum []byte) string
+	// Header 签名写入的请求头, 默认 Authorization
+	Header string
+	// Format 请求头的值, 默认 "HMAC {KeyID}:{signature}"
+	Format func(keyID, signature string) string
+
+	now func() time.Time // 当前时间, 测试用
+}
+
+// SignHMAC 使用 HMAC 签名每次发送的请求
+func SignHMAC(s *HMACSigner) Option {
+	return Transport(s.Mw)
+}
+
+// Mw 请求中间件, 签名每次发送的请求(包括重试和跳转)
+func (s *HMACSigner) Mw(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		signed := req.Clone(req.Context())
+		if err := s.Sign(signed); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(signed)
+	})
+}
+
+// Sign 签名请求, 没有 Date 请求头则设置为当前时间, 请求内容通过 GetBody 读取, 不会被消耗
+func (s *HMACSigner) Sign(req *http.Request) error {
+	if req.Header.Get(HeaderDate) == "" {
+		now := time.Now
+		if s.now != nil {
+			now = s.now
+		}
+		req.Header.Set(HeaderDate, now().UTC().Format(http.TimeFormat))
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	canonicalize := s.Canonicalize
+	if canonicalize == nil {
+		canonicalize = CanonicalHMAC
+	}
+	message, err := canonicalize(req, body)
+	if err != nil {
+		return err
+	}
+
+	h := s.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	sum := hmacSum(h, s.Secret, []byte(message))
+
+	signature := base64.StdEncoding.EncodeToString(sum)
+	if s.Encode != nil {
+		signature = s.Encode(sum)
+	}
+
+	value := "HMAC " + s.KeyID + ":" + signature
+	if s.Format != nil {
+		value = s.Format(s.KeyID, signature)
+	}
+
+	header := s.Header
+	if header == "" {
+		header = HeaderAuthorization
+	}
+	req.Header.Set(header, value)
+	return nil
+}
+
+// CanonicalHMAC 默认的待签名字符串, 每行一项:
+//
+//	METHOD
+//	/path?query
+//	Date 请求头
+//	Content-Type 请求头
+//	请求内容 SHA256 摘要的十六进制
+func CanonicalHMAC(req *http.Request, body []byte) (string, error) {
+	return strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		req.Header.Get(HeaderDate),
+		req.Header.Get(HeaderContentType),
+		hashHex(sha256.New, body),
+	}, "\n"), nil
+}
+
+// hashHex 摘要的十六进制
+func hashHex(h func() hash.Hash, data []byte) string {
+	w := h()
+	w.Write(data)
+	return hex.EncodeToString(w.Sum(nil))
+}
+
+// hmacSum HMAC 摘要
+func hmacSum(h func() hash.Hash, key, data []byte) []byte {
+	m := hmac.New(h, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
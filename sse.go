@@ -0,0 +1,220 @@
+package urlx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderLastEventID 重新连接时发送最后收到的事件 ID
+const HeaderLastEventID = "Last-Event-ID"
+
+// DefaultSSERetry 服务器没有指定 retry 时重新连接的等待时间
+const DefaultSSERetry = 3 * time.Second
+
+// ErrNotEventStream 响应不是 text/event-stream
+var ErrNotEventStream = errors.New("sse: response is not text/event-stream")
+
+// Event Server-Sent Events 事件
+type Event struct {
+	ID    string        // 最后的事件 ID, 没有设置时沿用之前的
+	Event string        // 事件类型, 默认 message
+	Data  string        // 数据, 多行 data 以换行连接
+	Retry time.Duration // 本事件中服务器指定的重新连接时间, 没有为0
+}
+
+// SSE 解析 text/event-stream 响应, 每个事件调用 handler, handler 返回错误时停止, 不会重新连接
+func SSE(handler func(event Event) error) Process {
+	return func(resp *http.Response) error {
+		return (&sseState{}).parse(resp.Body, handler)
+	}
+}
+
+// Stream 订阅 Server-Sent Events, 每个事件调用 handler. 连接使用请求的重试策略,
+// 连接建立后断开则等待服务器指定的 retry 时间(默认 DefaultSSERetry), 使用 Last-Event-ID 重新连接, 重新连接失败时继续等待重试.
+// Context 结束, handler 返回错误, 服务器返回 204, 非 200 响应, 事件流检查之前的处理器(如 CheckStatus)返回错误或首次连接失败时返回.
+// 只有 200 text/event-stream 响应读取结束或出错, 以及连接建立后的网络错误会重新连接
+func (c *Request) Stream(handler func(event Event) error) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	state := &sseState{retry: DefaultSSERetry}
+
+	var established bool
+	for {
+		r := c.Clone().HeaderWith(HeaderSet(HeaderAccept, "text/event-stream"), HeaderSet("Cache-Control", "no-cache"))
+		if lastID := state.lastID; lastID != "" {
+			r.HeaderWith(HeaderSet(HeaderLastEventID, lastID))
+		}
+
+		var responded, connected, stopped bool
+		err := r.ProcessWith(func(next Process) Process {
+			return func(resp *http.Response) error {
+				responded = true
+				return next(resp)
+			}
+		}).Process(func(resp *http.Response) error {
+			if resp.StatusCode == http.StatusNoContent {
+				stopped = true
+				return nil
+			}
+			if resp.StatusCode != http.StatusOK {
+				stopped = true
+				return newHTTPError(resp)
+			}
+			if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get(HeaderContentType)); mediaType != "text/event-stream" {
+				stopped = true
+				return fmt.Errorf("%w: %s", ErrNotEventStream, mediaType)
+			}
+			connected, established = true, true
+			return state.parse(resp.Body, func(event Event) error {
+				if err := handler(event); err != nil {
+					stopped = true
+					return err
+				}
+				return nil
+			})
+		})
+
+		var he *HTTPError
+		switch {
+		case stopped, errors.As(err, &he):
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case responded && !connected:
+			// 收到了响应, 但在事件流检查之前被处理器拦截
+			return err
+		case !established:
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(state.retry):
+		}
+	}
+}
+
+// Events 订阅 Server-Sent Events, 通过 channel 接收事件, 参见 Stream.
+// 结束后 channel 被关闭, wait 等待结束并返回错误. 不再接收时需要取消请求的 Context
+func (c *Request) Events(buffer int) (events <-chan Event, wait func() error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ch, done := make(chan Event, buffer), make(chan struct{})
+	var err error
+	go func() {
+		defer close(done)
+		defer close(ch)
+		err = c.Stream(func(event Event) error {
+			select {
+			case ch <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return ch, func() error { <-done; return err }
+}
+
+// sseState 解析状态, 在重新连接之间保留
+type sseState struct {
+	lastID string
+	retry  time.Duration
+}
+
+// parse 按 HTML 标准解析事件流, 流结束时未完成的事件被丢弃
+func (s *sseState) parse(r io.Reader, handler func(event Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	scanner.Split(scanSSELines)
+
+	var (
+		event Event
+		data  strings.Builder
+		first = true
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line, first = strings.TrimPrefix(line, "\ufeff"), false
+		}
+
+		if line == "" {
+			// 空行分派事件
+			if data.Len() > 0 {
+				event.ID, event.Data = s.lastID, strings.TrimSuffix(data.String(), "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if err := handler(event); err != nil {
+					return err
+				}
+			}
+			event = Event{}
+			data.Reset()
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = s.retry
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanSSELines 按 \r\n, \n 或 \r 分行
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// \r 后可能是 \n, 需要更多数据才能判断
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,148 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	var conns int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&conns, 1)
+		if r.URL.Path == "/json" {
+			_, _ = rw.Write([]byte("{}"))
+			return
+		}
+		rw.Header().Set(HeaderContentType, "text/event-stream; charset=utf-8")
+		switch n {
+		case 1:
+			_, _ = rw.Write([]byte("\ufeff: comment\nretry: 10\nid: 1\ndata: a\ndata:b\n\nevent: update\nid: 2\r\ndata: c\r\rdata: incomplete"))
+		case 2:
+			_, _ = rw.Write([]byte("data: " + r.Header.Get(HeaderLastEventID) + "\n\n"))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer closer()
+
+	var events []Event
+	start := time.Now()
+	err := New(context.TODO()).Url(addr).Stream(func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	eq(t, [][2]any{{err, nil}, {len(events), 3}, {atomic.LoadInt32(&conns), int32(3)}, {time.Since(start) >= 20*time.Millisecond, true}})
+	eq(t, [][2]any{
+		{events[0], Event{ID: "1", Event: "message", Data: "a\nb", Retry: 10 * time.Millisecond}},
+		{events[1], Event{ID: "2", Event: "update", Data: "c"}},
+		{events[2], Event{ID: "2", Event: "message", Data: "2"}},
+	})
+
+	// handler 返回错误时停止
+	atomic.StoreInt32(&conns, 0)
+	stop := errors.New("stop")
+	err = New(context.TODO()).Url(addr).Stream(func(event Event) error { return stop })
+	eq(t, [][2]any{{err, stop}, {atomic.LoadInt32(&conns), int32(1)}})
+
+	// 通过 channel 接收
+	atomic.StoreInt32(&conns, 0)
+	ch, wait := New(context.TODO()).Url(addr).Events(0)
+	var data []string
+	for event := range ch {
+		data = append(data, event.Data)
+	}
+	eq(t, [][2]any{{wait(), nil}, {strings.Join(data, "|"), "a\nb|c|2"}})
+
+	err = New(context.TODO()).Url(addr + "/json").Stream(func(event Event) error { return nil })
+	eq(t, [][2]any{{errors.Is(err, ErrNotEventStream), true}})
+}
+
+func TestSSEReconnectFailed(t *testing.T) {
+	var conns int32
+	dropped := make(chan struct{}, 100)
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// 不复用连接, 以免 http.Transport 自动重试断开的连接
+		rw.Header().Set("Connection", "close")
+		switch n := atomic.AddInt32(&conns, 1); {
+		case n == 1 || n == 100:
+			rw.Header().Set(HeaderContentType, "text/event-stream")
+			_, _ = rw.Write([]byte("retry: 10\nid: 1\ndata: a\n\n"))
+		case n == 2 || n == 3 || n > 100:
+			// 重新连接时服务器直接断开连接
+			if conn, _, err := rw.(http.Hijacker).Hijack(); err == nil {
+				_ = conn.Close()
+			}
+			if n > 100 {
+				dropped <- struct{}{}
+			}
+		case n == 4:
+			rw.Header().Set(HeaderContentType, "text/event-stream")
+			_, _ = rw.Write([]byte("data: " + r.Header.Get(HeaderLastEventID) + "\n\n"))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer closer()
+
+	var data []string
+	start := time.Now()
+	err := New(context.TODO()).Url(addr).Stream(func(event Event) error {
+		data = append(data, event.Data)
+		return nil
+	})
+	eq(t, [][2]any{{err, nil}, {strings.Join(data, "|"), "a|1"}, {atomic.LoadInt32(&conns), int32(5)}, {time.Since(start) >= 40*time.Millisecond, true}})
+
+	// 重新连接一直失败时继续重试, 直到 Context 结束
+	atomic.StoreInt32(&conns, 99)
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	go func() {
+		<-dropped
+		<-dropped
+		cancel()
+	}()
+	err = New(ctx).Url(addr).Stream(func(event Event) error { return nil })
+	eq(t, [][2]any{{err, context.Canceled}, {atomic.LoadInt32(&conns) >= 102, true}})
+
+	// 首次连接失败时返回
+	err = New(context.TODO()).Url("http://127.0.0.1:1").Stream(func(event Event) error { return nil })
+	eq(t, [][2]any{{err != nil, true}})
+}
+
+func TestSSEReconnectProcessError(t *testing.T) {
+	var conns int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&conns, 1) {
+		case 1:
+			rw.Header().Set(HeaderContentType, "text/event-stream")
+			_, _ = rw.Write([]byte("retry: 1\ndata: a\n\n"))
+		case 2:
+			rw.WriteHeader(http.StatusUnauthorized)
+		default:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer closer()
+
+	// 重新连接时 CheckStatus 返回的错误结束订阅
+	err := New(context.TODO()).Url(addr).ProcessWith(CheckStatus).Stream(func(event Event) error { return nil })
+	eq(t, [][2]any{{StatusOf(err), http.StatusUnauthorized}, {atomic.LoadInt32(&conns), int32(2)}})
+
+	// 事件流检查之前的处理器返回的其他错误也结束订阅
+	atomic.StoreInt32(&conns, 0)
+	denied := errors.New("denied")
+	err = New(context.TODO()).Url(addr).ProcessWith(func(next Process) Process {
+		return func(resp *http.Response) error {
+			if atomic.LoadInt32(&conns) > 1 {
+				return denied
+			}
+			return next(resp)
+		}
+	}).Stream(func(event Event) error { return nil })
+	eq(t, [][2]any{{err, denied}, {atomic.LoadInt32(&conns), int32(2)}})
+}
@@ -0,0 +1,132 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAttemptTimeout 单次请求超时, 默认的重试策略会重试
+var ErrAttemptTimeout = errors.New("attempt timeout")
+
+// AttemptTimeout 设置单次请求的超时时间, 与 Context 的整体截止时间分开计算, 超时后按重试策略重试.
+// withBody 为 true 时包括读取响应内容的时间, 否则只包括连接和等待响应头.
+// 读取响应内容超时时, 只有处理器还没有读到任何内容才会重试, 避免处理器(如下载, SSE)重复执行
+func (c *Request) AttemptTimeout(timeout time.Duration, withBody ...bool) *Request {
+	c.attemptTimeout = timeout
+	c.attemptWithBody = len(withBody) > 0 && withBody[0]
+	return c
+}
+
+// AttemptTimeout 设置单次请求的超时时间
+func AttemptTimeout(timeout time.Duration, withBody ...bool) Option {
+	return func(r *Request) error {
+		r.AttemptTimeout(timeout, withBody...)
+		return nil
+	}
+}
+
+// attemptTimeoutError 单次请求超时的错误
+type attemptTimeoutError struct{ err error }
+
+func (e *attemptTimeoutError) Error() string        { return ErrAttemptTimeout.Error() + ": " + e.err.Error() }
+func (e *attemptTimeoutError) Unwrap() error        { return e.err }
+func (e *attemptTimeoutError) Is(target error) bool { return target == ErrAttemptTimeout }
+func (e *attemptTimeoutError) Timeout() bool        { return true }
+func (e *attemptTimeoutError) Temporary() bool      { return true }
+
+// attemptTimer 单次请求的计时, 超时只取消本次请求
+type attemptTimer struct {
+	cancel   context.CancelFunc
+	timer    *time.Timer
+	withBody bool
+	expired  int32
+	consumed int32 // 处理器已经读到了响应内容
+}
+
+// attemptContext 创建单次请求的 Context
+func (c *Request) attemptContext() (context.Context, *attemptTimer) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	t := &attemptTimer{cancel: cancel, withBody: c.attemptWithBody}
+	if c.attemptTimeout > 0 {
+		t.timer = time.AfterFunc(c.attemptTimeout, func() {
+			atomic.StoreInt32(&t.expired, 1)
+			cancel()
+		})
+	}
+	return &attemptCtx{Context: ctx, t: t}, t
+}
+
+// attemptCtx 超时后 Err 返回 context.DeadlineExceeded, 以便与调用方取消区分
+type attemptCtx struct {
+	context.Context
+	t *attemptTimer
+}
+
+func (c *attemptCtx) Err() error {
+	err := c.Context.Err()
+	if err != nil && c.t.Expired() {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// Expired 是否已经超时
+func (t *attemptTimer) Expired() bool {
+	return atomic.LoadInt32(&t.expired) == 1
+}
+
+// Consumed 处理器是否已经读到了响应内容
+func (t *attemptTimer) Consumed() bool {
+	return atomic.LoadInt32(&t.consumed) == 1
+}
+
+// stop 停止计时
+func (t *attemptTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// watch 收到响应头后, 不包括响应内容则停止计时; 响应内容关闭时释放 Context
+func (t *attemptTimer) watch(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		t.stop()
+		t.cancel()
+		if t.Expired() {
+			err = &attemptTimeoutError{err}
+		}
+		return resp, err
+	}
+	if !t.withBody {
+		t.stop()
+	}
+	resp.Body = &attemptBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+// attemptBody 响应内容, 读取超时返回 ErrAttemptTimeout
+type attemptBody struct {
+	io.ReadCloser
+	t *attemptTimer
+}
+
+func (b *attemptBody) Read(p []byte) (n int, err error) {
+	if n, err = b.ReadCloser.Read(p); n > 0 {
+		atomic.StoreInt32(&b.t.consumed, 1)
+	}
+	if err != nil && err != io.EOF && b.t.Expired() {
+		err = &attemptTimeoutError{err}
+	}
+	return
+}
+
+func (b *attemptBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.t.stop()
+	b.t.cancel()
+	return err
+}
@@ -0,0 +1,71 @@
+package urlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptTimeout(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			time.Sleep(300 * time.Millisecond)
+		case 2:
+			rw.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+		}
+		_, _ = rw.Write([]byte("ok"))
+	}))
+	defer closer()
+
+	data, err := New(context.TODO()).Url(addr).TryAt(0, 0).AttemptTimeout(100*time.Millisecond, true).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{{string(data), "ok"}, {atomic.LoadInt32(&hits), int32(3)}})
+
+	atomic.StoreInt32(&hits, 0)
+	err = New(context.TODO()).Url(addr).AttemptTimeout(100 * time.Millisecond).Process(nil)
+	if !errors.Is(err, ErrAttemptTimeout) {
+		t.Fatalf("want ErrAttemptTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	atomic.StoreInt32(&hits, 0)
+	err = New(ctx).Url(addr).TryAt(0, 0).AttemptTimeout(time.Second).Process(nil)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrAttemptTimeout) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	eq(t, [][2]any{{atomic.LoadInt32(&hits), int32(1)}})
+}
+
+func TestAttemptTimeoutConsumed(t *testing.T) {
+	var hits int32
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = rw.Write([]byte("partial"))
+		rw.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer closer()
+
+	// 处理器已经读到内容后超时, 不再重试, 处理器只执行一次
+	var runs int32
+	err := New(context.TODO()).Url(addr).TryAt(0, 0).AttemptTimeout(100*time.Millisecond, true).
+		Process(func(resp *http.Response) error {
+			atomic.AddInt32(&runs, 1)
+			_, err := io.ReadAll(resp.Body)
+			return err
+		})
+	if !errors.Is(err, ErrAttemptTimeout) {
+		t.Fatalf("want ErrAttemptTimeout, got %v", err)
+	}
+	eq(t, [][2]any{{atomic.LoadInt32(&runs), int32(1)}, {atomic.LoadInt32(&hits), int32(1)}})
+}
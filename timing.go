@@ -0,0 +1,183 @@
+package urlx
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing 一次往返(每次重试和跳转)的耗时分解, 没有发生的阶段为0
+type Timing struct {
+	Method     string        // 请求方法
+	URL        string        // 请求地址, 隐藏了密码
+	Start      time.Time     // 开始发送的时间
+	DNS        time.Duration // 域名解析
+	Connect    time.Duration // 建立 TCP 连接
+	TLS        time.Duration // TLS 握手
+	TTFB       time.Duration // 从开始到收到响应的第一个字节
+	Transfer   time.Duration // 从收到第一个字节到读完响应内容
+	Total      time.Duration // 从开始到读完响应内容, 未读完时为到目前为止
+	Reused     bool          // 是否复用了连接
+	RemoteAddr string        // 服务器地址
+	Err        error         // 请求错误
+}
+
+// TraceTiming 使用 httptrace 记录每次往返的耗时, 在处理中通过 TimingOf 获取,
+// report 在每次往返的响应内容读完或关闭后(请求失败时立即)调用
+func TraceTiming(report ...func(timing Timing)) Option {
+	return Transport(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			resp, _, err := traceRoundTrip(next, req, report)
+			return resp, err
+		})
+	})
+}
+
+// traceRoundTrip 发送请求并记录耗时, 响应内容读完或关闭时结束计时
+func traceRoundTrip(next http.RoundTripper, req *http.Request, report []func(timing Timing)) (*http.Response, *timingTrace, error) {
+	trace := &timingTrace{report: report}
+	trace.timing.Method, trace.timing.URL, trace.timing.Start = req.Method, req.URL.Redacted(), time.Now()
+
+	ctx := context.WithValue(req.Context(), timingKey{}, trace)
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	resp, err := next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		trace.finish(err)
+		return nil, trace, err
+	}
+	resp.Body = &timingBody{ReadCloser: resp.Body, trace: trace}
+	return resp, trace, nil
+}
+
+// TimingOf 响应的耗时, 没有启用 TraceTiming 返回 false
+func TimingOf(resp *http.Response) (Timing, bool) {
+	if resp == nil || resp.Request == nil {
+		return Timing{}, false
+	}
+	trace, ok := resp.Request.Context().Value(timingKey{}).(*timingTrace)
+	if !ok {
+		return Timing{}, false
+	}
+	return trace.snapshot(), true
+}
+
+// ProgressTimingReport 带耗时的进度报告方法
+type ProgressTimingReport = func(total float64, cur float64, speed float64, timing Timing)
+
+// ProgressTiming 下载进度, 同时报告请求的耗时, 需要启用 TraceTiming, 否则 timing 为零值
+func ProgressTiming(report ProgressTimingReport, reportInterval ...time.Duration) ProcessMw {
+	return func(next Process) Process {
+		return func(resp *http.Response) error {
+			return Progress(func(total, cur, speed float64) {
+				timing, _ := TimingOf(resp)
+				report(total, cur, speed, timing)
+			}, reportInterval...)(next)(resp)
+		}
+	}
+}
+
+type timingKey struct{}
+
+// timingTrace 记录一次往返的耗时, 连接相关的回调可能在其他协程中执行
+type timingTrace struct {
+	mu     sync.Mutex
+	timing Timing
+	report []func(timing Timing)
+
+	dnsStart, connectStart, tlsStart, firstByte time.Time
+	done                                        bool
+}
+
+// clientTrace httptrace 回调
+func (t *timingTrace) clientTrace() *httptrace.ClientTrace {
+	lock := func(f func()) { t.mu.Lock(); defer t.mu.Unlock(); f() }
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { lock(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { lock(func() { t.timing.DNS = time.Since(t.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			lock(func() {
+				if t.connectStart.IsZero() {
+					t.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			lock(func() {
+				if err == nil {
+					t.timing.Connect = time.Since(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() { lock(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { lock(func() { t.timing.TLS = time.Since(t.tlsStart) }) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			lock(func() {
+				t.timing.Reused = info.Reused
+				if info.Conn != nil {
+					t.timing.RemoteAddr = info.Conn.RemoteAddr().String()
+				}
+			})
+		},
+		GotFirstResponseByte: func() {
+			lock(func() {
+				t.firstByte = time.Now()
+				t.timing.TTFB = t.firstByte.Sub(t.timing.Start)
+			})
+		},
+	}
+}
+
+// snapshot 当前的耗时
+func (t *timingTrace) snapshot() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timing
+	if !t.done {
+		timing.Total = time.Since(timing.Start)
+	}
+	return timing
+}
+
+// finish 往返结束, 只报告一次
+func (t *timingTrace) finish(err error) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	t.done = true
+	now := time.Now()
+	if !t.firstByte.IsZero() {
+		t.timing.Transfer = now.Sub(t.firstByte)
+	}
+	t.timing.Total, t.timing.Err = now.Sub(t.timing.Start), err
+	timing := t.timing
+	t.mu.Unlock()
+
+	for _, report := range t.report {
+		report(timing)
+	}
+}
+
+// timingBody 读完或关闭时结束计时
+type timingBody struct {
+	io.ReadCloser
+	trace *timingTrace
+}
+
+func (b *timingBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.trace.finish(nil)
+	}
+	return
+}
+
+func (b *timingBody) Close() error {
+	b.trace.finish(nil)
+	return b.ReadCloser.Close()
+}
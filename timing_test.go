@@ -0,0 +1,54 @@
+package urlx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceTiming(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" {
+			http.Redirect(rw, r, "/b", http.StatusFound)
+			return
+		}
+		rw.Header().Set("Content-Length", "4")
+		rw.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = rw.Write([]byte("done"))
+	}))
+	defer closer()
+
+	var (
+		mu      sync.Mutex
+		reports []Timing
+		speeds  []Timing
+	)
+	s := NewSession(TraceTiming(func(timing Timing) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, timing)
+	}))
+
+	for i := 0; i < 2; i++ {
+		var timing Timing
+		err := s.New(context.TODO()).Url(addr + "/a").
+			ProcessWith(ProgressTiming(func(total, cur, speed float64, timing Timing) { speeds = append(speeds, timing) })).
+			Process(func(resp *http.Response) (err error) {
+				if _, err = io.ReadAll(resp.Body); err == nil {
+					timing, _ = TimingOf(resp)
+				}
+				return
+			})
+		eq(t, [][2]any{{err, nil}, {timing.URL, addr + "/b"}, {timing.Reused, true}, {timing.RemoteAddr != "", true}})
+		eq(t, [][2]any{{timing.Transfer >= 20*time.Millisecond, true}, {timing.Total >= timing.TTFB+timing.Transfer, true}})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	eq(t, [][2]any{{len(reports), 4}, {reports[0].Connect > 0, true}, {reports[1].Reused, true}, {reports[3].Err, nil}})
+	eq(t, [][2]any{{speeds[len(speeds)-1].Transfer > 0, true}})
+}
@@ -0,0 +1,164 @@
+package urlx
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+/* 请求中间件 */
+
+// RequestMw 请求中间件, 包装客户端的 http.RoundTripper, 在请求发送前和收到响应头后处理, 每次重试和跳转都会执行
+type RequestMw = func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripFunc 函数形式的 http.RoundTripper
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip 实现 http.RoundTripper
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// TransportWith 增加请求中间件, 与 ProcessWith 相同, 后添加的在外层, 先处理请求, 后处理响应
+func (c *Request) TransportWith(mws ...RequestMw) *Request {
+	c.transports = append(c.transports, mws...)
+	return c
+}
+
+// Transport 增加请求中间件
+func Transport(mws ...RequestMw) Option {
+	return func(r *Request) error {
+		r.TransportWith(mws...)
+		return nil
+	}
+}
+
+// TransportWith 增加默认的请求中间件
+func (s *Session) TransportWith(mws ...RequestMw) *Session {
+	return s.update(func(tpl *Request) { tpl.TransportWith(mws...) })
+}
+
+// HeaderMw 在发送时处理请求头, 与 HeaderWith 不同的是跳转后的请求也会处理.
+// 跨主机跳转时不会添加 Authorization, Cookie 等敏感请求头, 保持跳转策略的处理结果
+func HeaderMw(options ...HeaderOption) RequestMw {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			origin := sameOrigin(req)
+			sent := req.Clone(req.Context())
+			for _, headerOption := range options {
+				headerOption(sent.Header)
+			}
+			if !origin {
+				for _, name := range sensitiveHeaders {
+					if values := req.Header.Values(name); len(values) > 0 {
+						sent.Header[http.CanonicalHeaderKey(name)] = values
+					} else {
+						sent.Header.Del(name)
+					}
+				}
+			}
+			return next.RoundTrip(sent)
+		})
+	}
+}
+
+// sameOrigin 请求不是跳转, 或者跳转到了与第一个请求相同的源(scheme 和主机), 可以添加认证信息
+func sameOrigin(req *http.Request) bool {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	return originOf(req.URL) == originOf(first.URL)
+}
+
+// originOf 链接的源 scheme://host
+func originOf(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+// ProcessMwRoundTrip 在每次往返(包括跳转)收到的响应上执行响应预处理, 预处理返回错误则请求失败
+func ProcessMwRoundTrip(mws ...ProcessMw) RequestMw {
+	process := ProcessNil
+	for _, mw := range mws {
+		process = mw(process)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if err = process(resp); err != nil {
+				closes(resp.Body)
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// roundTripper 组合代理和请求中间件
+func (c *Request) roundTripper() (http.RoundTripper, error) {
+	rt := c.client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if c.proxy != nil {
+		var err error
+		if rt, err = proxyTransport(rt, c.proxy); err != nil {
+			return nil, err
+		}
+	}
+	for _, mw := range c.transports {
+		rt = mw(rt)
+	}
+	return rt, nil
+}
+
+// proxyTransportMax 最多缓存的代理 Transport 数量
+const proxyTransportMax = 32
+
+// proxyTransports 使用代理的 http.Transport, 相同的基础 Transport 和代理复用同一个, 以便复用连接,
+// 超过 proxyTransportMax 时淘汰最久未使用的, 并关闭其空闲连接
+var proxyTransports = struct {
+	sync.Mutex
+	order *list.List
+	items map[proxyKey]*list.Element
+}{order: list.New(), items: map[proxyKey]*list.Element{}}
+
+type proxyKey struct {
+	base  *http.Transport
+	proxy string
+}
+
+type proxyEntry struct {
+	key       proxyKey
+	transport *http.Transport
+}
+
+// proxyTransport 复制基础 Transport 并设置代理
+func proxyTransport(base http.RoundTripper, proxy *url.URL) (http.RoundTripper, error) {
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("proxy: transport %T is not *http.Transport", base)
+	}
+	key := proxyKey{base: t, proxy: proxy.String()}
+
+	proxyTransports.Lock()
+	defer proxyTransports.Unlock()
+	if el, ok := proxyTransports.items[key]; ok {
+		proxyTransports.order.MoveToFront(el)
+		return el.Value.(*proxyEntry).transport, nil
+	}
+
+	clone := t.Clone()
+	clone.Proxy = http.ProxyURL(proxy)
+	proxyTransports.items[key] = proxyTransports.order.PushFront(&proxyEntry{key: key, transport: clone})
+	for proxyTransports.order.Len() > proxyTransportMax {
+		evicted := proxyTransports.order.Remove(proxyTransports.order.Back()).(*proxyEntry)
+		delete(proxyTransports.items, evicted.key)
+		evicted.transport.CloseIdleConnections()
+	}
+	return clone, nil
+}
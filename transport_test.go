@@ -0,0 +1,59 @@
+package urlx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTransportWith(t *testing.T) {
+	addr, closer := mockHTTPServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/from" {
+			http.Redirect(rw, r, "/to", http.StatusFound)
+			return
+		}
+		_, _ = rw.Write([]byte(r.Header.Get("X-Sign")))
+	}))
+	defer closer()
+
+	var order []string
+	mark := func(name string) RequestMw {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" "+req.URL.Path)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	data, err := New(context.TODO()).Url(addr+"/from").
+		TransportWith(mark("inner"), HeaderMw(HeaderSet("X-Sign", "signed")), mark("outer")).
+		Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, [][2]any{
+		{string(data), "signed"},
+		{strings.Join(order, ","), "outer /from,inner /from,outer /to,inner /to"},
+	})
+}
+
+func TestProxyTransportEvict(t *testing.T) {
+	base := &http.Transport{}
+	proxy := func(i int) *url.URL { return &url.URL{Scheme: "http", Host: "proxy" + strconv.Itoa(i) + ":8080"} }
+
+	first, _ := proxyTransport(base, proxy(0))
+	again, _ := proxyTransport(base, proxy(0))
+	for i := 1; i <= proxyTransportMax; i++ {
+		_, _ = proxyTransport(base, proxy(i))
+	}
+	evicted, _ := proxyTransport(base, proxy(0))
+
+	proxyTransports.Lock()
+	size := len(proxyTransports.items)
+	proxyTransports.Unlock()
+	eq(t, [][2]any{{again == first, true}, {evicted == first, false}, {size <= proxyTransportMax, true}})
+}
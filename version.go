@@ -18,5 +18,5 @@ func DefaultUserAgent() HeaderOption {
 // Default 默认的请求器
 func Default(ctx context.Context) *Request {
 	ms := time.Millisecond
-	return New(ctx).HeaderWith(DefaultUserAgent()).TryAt(ms*300, ms*800, ms*1500)
+	return New(ctx).HeaderWith(DefaultUserAgent()).TryAt(ms*300, ms*800, ms*1500).ProcessWith(CheckStatus)
 }